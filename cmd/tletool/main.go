@@ -1,14 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"encoding/xml"
 	"flag"
 	"fmt"
 	"hash/fnv"
 	"log"
 	"math/rand"
 	"os"
+	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/morphism/gpelements"
@@ -23,44 +25,65 @@ func main() {
 func run() error {
 
 	var (
-		ts = func(t time.Time) string {
-			return t.Format(time.RFC3339Nano)
-		}
-
 		now = time.Now().UTC()
 
 		generic  = flag.NewFlagSet("generic", flag.ContinueOnError)
 		bufSize  = generic.Int("buf-size", 4096, "Buffer size")
-		tolerate = generic.Bool("tolerate", false, "Log errors instead of stopping")
+		tolerate = generic.Bool("tolerate", false, "Log errors (and parsing warnings) instead of stopping")
+		strip    = generic.Bool("strip-extras", false, "Drop input fields that aren't part of the OMM")
+		strict   = generic.String("strictness", "standard", "Parsing strictness: strict|standard|lenient")
+		eop      = generic.String("eop", "", "IERS finals file of Earth orientation parameters (for UT1)")
+		leaps    = generic.String("leap-seconds", "", "Leap second table (in the format of leap-seconds.list) to use instead of the built-in one")
+		yearFrom = generic.Int("year-start", gpelements.DefaultYearStart, "First year of the window for two-digit TLE years")
+		base62   = generic.Bool("base62", false, "Write catalog numbers beyond Alpha-5's range in TLEs in base 62 (which other software won't understand)")
+		input    = generic.String("input", "", "Input represention (detected if not given): "+strings.Join(gpelements.CodecNames(), "|"))
 		help     = generic.Bool("help", false, "Just get help")
 
 		transform = flag.NewFlagSet("transform", flag.ExitOnError)
-		emit      = transform.String("emit", "csv", "Output represention: csv|csvh|json|jsonarray|tle|kvn|xml")
+		emit      = transform.String("emit", "csv", "Output represention: "+strings.Join(gpelements.CodecNames(), "|"))
+		columns   = transform.String("columns", "", "Comma-separated OMM keywords for csv|csvh columns")
 
 		prop                = flag.NewFlagSet("prop", flag.ExitOnError)
-		propFrom            = prop.String("from", ts(now), "Propagation start time")
+		propFrom            = prop.String("from", "now", "Propagation start time")
 		propTo              = prop.String("to", "", "Propagation end time")
 		propInterval        = prop.Duration("interval", time.Minute, "Propagation end time")
 		propHigher          = prop.Bool("higher-precision", true, "Higher-precision (as able)")
 		propDuration        = prop.Duration("duration", 0, "Duration of propagation (instead of -to)")
+		propFrame           = prop.String("frame", "teme", "Frame of the state: teme|gcrf|itrf")
+		propObserver        = prop.String("observer", "", "Observer's LAT,LON,ALT (degrees, degrees, km) for look angles")
+		propShadow          = prop.String("shadow", "conical", "Shadow model for illumination: conical|cylindrical")
 		propDurationDefault = 10 * time.Minute
 
 		orbit         = flag.NewFlagSet("on-orbit", flag.ExitOnError)
-		orbitFrom     = orbit.String("from", ts(now), "Propagation start time")
-		orbitTo       = orbit.String("to", ts(now.Add(time.Hour)), "Propagation end time")
+		orbitFrom     = orbit.String("from", "now", "Propagation start time")
+		orbitTo       = orbit.String("to", "now+1h", "Propagation end time")
 		orbitInterval = orbit.Duration("interval", 10*time.Minute, "Propagation end time")
 
+		passes         = flag.NewFlagSet("passes", flag.ExitOnError)
+		passesFrom     = passes.String("from", "now", "Search start time")
+		passesTo       = passes.String("to", "", "Search end time")
+		passesDuration = passes.Duration("duration", 24*time.Hour, "Duration of the search (instead of -to)")
+		passesObserver = passes.String("observer", "", "Observer's LAT,LON,ALT (degrees, degrees, km) (required)")
+		passesMask     = passes.Float64("mask", 0, "Minimum elevation (degrees)")
+		passesEmit     = passes.String("emit", "csv", "Output represention: csv|csvh|json|jsonarray")
+
+		eclipses         = flag.NewFlagSet("eclipses", flag.ExitOnError)
+		eclipsesFrom     = eclipses.String("from", "now", "Search start time")
+		eclipsesTo       = eclipses.String("to", "", "Search end time")
+		eclipsesDuration = eclipses.Duration("duration", 24*time.Hour, "Duration of the search (instead of -to)")
+		eclipsesShadow   = eclipses.String("shadow", "conical", "Shadow model: conical|cylindrical")
+
 		walk           = flag.NewFlagSet("walk", flag.ExitOnError)
 		minSteps       = walk.Int("min-steps", 1, "Minimum number of steps")
 		maxSteps       = walk.Int("max-steps", 3, "Maximum number of steps")
 		incSet         = walk.Bool("inc-set", true, "Increment element set number")
-		walkResetEpoch = walk.String("reset-epoch", "", "Set Epoch to this time ('now' shorthand)")
+		walkResetEpoch = walk.String("reset-epoch", "", "Set Epoch to this time (like 'now' or 'epoch+90m')")
 		seed           = walk.Int64("seed", time.Now().UTC().UnixNano(), "RNG seed (defaults to current time in ns)")
 
 		rename           = flag.NewFlagSet("rename", flag.ExitOnError)
 		renameState      = rename.Int64("state", 0, "Next catalog number in Alpha-5 A range")
 		renameClear      = rename.Bool("clear", false, "Remove original name (suffix)")
-		renameResetEpoch = rename.String("reset-epoch", "", "Set epoch to this time ('now' shorthand)")
+		renameResetEpoch = rename.String("reset-epoch", "", "Set epoch to this time (like 'now' or 'epoch+90m')")
 
 		sample    = flag.NewFlagSet("sample", flag.ExitOnError)
 		sampleMod = sample.Int("mod", 10, "Sampling hash modulus")
@@ -71,7 +94,11 @@ func run() error {
 	)
 
 	usage := func() {
-		fmt.Fprintf(os.Stderr, `Usage: %s transform|prop|on-orbit|walk|rename|sample|random ...
+		fmt.Fprintf(os.Stderr, `Usage: %s transform|prop|passes|eclipses|on-orbit|walk|rename|sample|random ... [FILE ...]
+
+Input is read from the given files (or glob patterns) in order, or
+from stdin if there aren't any.  Each file's representation is
+detected separately, and compressed files are decompressed.
 
 Subcommands:
 
@@ -86,6 +113,15 @@ Subcommands:
 
   csvh emits CSV output with a header line.
 
+  columns chooses the OMM keywords (and their order) that csv and
+  csvh emit.  The default is Celestrak's layout, which always quotes
+  OBJECT_NAME, OBJECT_ID, EPOCH, CLASSIFICATION_TYPE, and
+  NORAD_CAT_ID.  Other values are quoted only when they need to be.
+  Without columns,
+  csvh adds the first element set's extra fields to the header, and
+  a later element set with other extra fields is an error (unless
+  they're removed with -strip-extras).
+
   json emits each element set as a single line of JSON.
 
   jsonarray emits an array of element sets as one big blob of JSON.
@@ -95,6 +131,12 @@ Subcommands:
 		fmt.Fprintf(os.Stderr, "\n  prop: Propagate\n\n")
 		prop.PrintDefaults()
 
+		fmt.Fprintf(os.Stderr, "\n  passes: Find when objects are above an observer's elevation mask\n\n")
+		passes.PrintDefaults()
+
+		fmt.Fprintf(os.Stderr, "\n  eclipses: Find when objects enter and leave the Earth's shadow\n\n")
+		eclipses.PrintDefaults()
+
 		fmt.Fprintf(os.Stderr, "\n  on-orbit: Filter for on-orbit\n\n")
 		orbit.PrintDefaults()
 
@@ -141,25 +183,32 @@ Subcommands:
 		args       = os.Args[next+1:]
 	)
 
+	var flags *flag.FlagSet
 	switch subcommand {
 	case "transform":
-		transform.Parse(args)
+		flags = transform
 	case "prop":
-		prop.Parse(args)
+		flags = prop
+	case "passes":
+		flags = passes
+	case "eclipses":
+		flags = eclipses
 	case "on-orbit", "orbit":
-		orbit.Parse(args)
+		flags = orbit
 	case "walk":
-		walk.Parse(args)
+		flags = walk
 	case "rename":
-		rename.Parse(args)
+		flags = rename
 	case "sample":
-		sample.Parse(args)
+		flags = sample
 	case "random":
-		random.Parse(args)
+		flags = random
 	default:
 		usage()
 		os.Exit(1)
 	}
+	flags.Parse(args)
+	filenames := flags.Args()
 
 	rand.Seed(*seed)
 
@@ -167,94 +216,234 @@ Subcommands:
 		*bufSize = gpelements.MinBufferSize
 	}
 
-	t0, err := time.Parse(time.RFC3339Nano, *propFrom)
-	if err != nil {
-		return err
+	// Times can be relative to an element set's epoch, so they're
+	// checked now but evaluated for each element set.
+	at := func(s string, e *gpelements.Elements) (time.Time, error) {
+		var epoch time.Time
+		if e.Epoch != nil {
+			epoch = time.Time(*e.Epoch)
+		}
+		return gpelements.ParseTimeExpr(s, now, epoch)
+	}
+
+	// window evaluates a -from and then a -to or, if that's empty, a
+	// -duration.
+	window := func(from, to string, d time.Duration, e *gpelements.Elements) (time.Time, time.Time, error) {
+		t0, err := at(from, e)
+		if err != nil || to == "" {
+			return t0, t0.Add(d), err
+		}
+		t1, err := at(to, e)
+		return t0, t1, err
+	}
+	for _, s := range []string{*propFrom, *propTo, *passesFrom, *passesTo, *eclipsesFrom, *eclipsesTo, *orbitFrom, *orbitTo, *renameResetEpoch, *walkResetEpoch} {
+		if s == "" {
+			continue
+		}
+		if _, err := gpelements.ParseTimeExpr(s, now, now); err != nil {
+			return err
+		}
 	}
 
-	var t1 time.Time
 	if *propTo != "" {
 		if 0 < *propDuration {
 			fmt.Fprintf(os.Stderr, "Don't give both -to and -duration")
 			os.Exit(1)
 		}
-		if t1, err = time.Parse(time.RFC3339Nano, *propTo); err != nil {
+	} else if 0 == *propDuration {
+		*propDuration = propDurationDefault
+	}
+
+	gpelements.HigherPrecisionSGP4 = *propHigher
+
+	frame, err := gpelements.ParseFrame(*propFrame)
+	if err != nil {
+		return err
+	}
+
+	var observer *gpelements.Observer
+	if *propObserver != "" {
+		if observer, err = gpelements.ParseObserver(*propObserver); err != nil {
 			return err
 		}
-	} else {
-		if 0 == *propDuration {
-			*propDuration = propDurationDefault
+	}
+
+	shadow := *propShadow
+	if subcommand == "eclipses" {
+		shadow = *eclipsesShadow
+	}
+	if gpelements.DefaultShadowModel, err = gpelements.ParseShadowModel(shadow); err != nil {
+		return err
+	}
+
+	var passesOut *PassWriter
+	if subcommand == "passes" {
+		if *passesObserver == "" {
+			return fmt.Errorf("passes needs an -observer")
 		}
-		t1 = t0.Add(*propDuration)
+		if observer, err = gpelements.ParseObserver(*passesObserver); err != nil {
+			return err
+		}
+		if passesOut, err = NewPassWriter(os.Stdout, *passesEmit); err != nil {
+			return err
+		}
+		// Finish the output even if there's an error.
+		defer passesOut.Close() // Ignore error.
 	}
 
-	gpelements.HigherPrecisionSGP4 = *propHigher
+	if gpelements.DefaultParseOptions.Strictness, err = gpelements.ParseStrictness(*strict); err != nil {
+		return err
+	}
 
-	var epoch *gpelements.Time
-	parseEpoch := func(s string) {
-		if s != "" {
-			if s == "now" {
-				s = time.Now().UTC().Format(time.RFC3339Nano)
-			}
-			t, err := time.Parse(s, time.RFC3339Nano)
-			if err != nil {
-				panic(fmt.Errorf("bad epoch: %v", err))
-			}
-			e := gpelements.Time(t)
-			epoch = &e
+	if *eop != "" {
+		if gpelements.DefaultEOP, err = gpelements.LoadEOP(*eop); err != nil {
+			return err
 		}
 	}
-	parseEpoch(*renameResetEpoch)
-	parseEpoch(*walkResetEpoch)
+
+	if *leaps != "" {
+		in, err := os.Open(*leaps)
+		if err != nil {
+			return err
+		}
+		gpelements.LeapSeconds, err = gpelements.ParseLeapSeconds(in)
+		in.Close() // Ignore error.
+		if err != nil {
+			return err
+		}
+	}
+
+	years := gpelements.YearWindow{Start: *yearFrom}
+	gpelements.DefaultParseOptions.Years = years
+	marshalOpts := gpelements.MarshalOptions{
+		Base62: *base62,
+		Years:  years,
+	}
+	gpelements.DefaultMarshalOptions = marshalOpts
+
+	resetEpoch := func(s string, e *gpelements.Elements) error {
+		if s == "" {
+			return nil
+		}
+		t, err := at(s, e)
+		if err != nil {
+			return err
+		}
+		e.Epoch = gpelements.NewTime(t)
+		return nil
+	}
 
 	state := *renameState
 
-	var (
-		i  = 0
-		es = make([]gpelements.Elements, 0, 1024)
-	)
+	i := 0
+
+	var out gpelements.ElementsWriter
+	if subcommand == "transform" {
+		c, have := gpelements.LookupCodec(*emit)
+		if !have {
+			return fmt.Errorf("unknown output representation '%s'", *emit)
+		}
+		out = c.NewWriter(os.Stdout, marshalOpts)
+
+		if *columns != "" {
+			switch *emit {
+			case "csv", "csvh":
+				csvColumns := strings.Split(*columns, ",")
+				out = gpelements.NewCSVWriter(os.Stdout, csvColumns, *emit == "csvh")
+			}
+		}
+	}
 
-	in := os.Stdin
-	defer in.Close()
+	opts := gpelements.DecodeOptions{
+		Format:  *input,
+		BufSize: *bufSize,
+		Parse:   gpelements.DefaultParseOptions,
+	}
+
+	if *tolerate {
+		opts.OnError = gpelements.SkipAndLog
+	}
+
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+
+	// Stop cleanly on an interrupt.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt)
+		select {
+		case <-sigs:
+			log.Printf("interrupted")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
-	err = gpelements.Do(in, *bufSize, func(e gpelements.Elements) error {
+	res, err := gpelements.DoFilesContext(ctx, filenames, opts, func(e gpelements.Elements) error {
 		var (
 			s   string
 			err error
 			bs  []byte
 		)
 
+		if *strip {
+			e.StripExtras()
+		}
+
+		if *tolerate {
+			for _, w := range e.Warnings {
+				log.Printf("at %d (%s) warning: %s", i, where(e), w)
+			}
+		}
+
 		switch subcommand {
 		case "transform":
-			switch *emit {
-			case "csv":
-				s, err = e.MarshalCSV()
-			case "csvh":
-				if s, err = e.MarshalCSV(); err == nil {
-					if i == 0 {
-						s = gpelements.CSVHeader + "\n" + s
-					}
+			err = out.Write(&e)
+		case "prop":
+			var t0, t1 time.Time
+			if t0, t1, err = window(*propFrom, *propTo, *propDuration, &e); err != nil {
+				break
+			}
+			err = Prop(&e, t0, t1, *propInterval, frame, observer, true)
+		case "passes":
+			var t0, t1 time.Time
+			if t0, t1, err = window(*passesFrom, *passesTo, *passesDuration, &e); err != nil {
+				break
+			}
+			var ps []gpelements.Pass
+			ps, err = e.Passes(observer, t0, t1, gpelements.PassOptions{Mask: *passesMask})
+			for _, p := range ps {
+				if err = passesOut.Write(NewPassRow(&e, p)); err != nil {
+					break
 				}
-			case "json":
-				if bs, err = json.Marshal(e); err == nil {
-					s = string(bs)
+			}
+		case "eclipses":
+			var t0, t1 time.Time
+			if t0, t1, err = window(*eclipsesFrom, *eclipsesTo, *eclipsesDuration, &e); err != nil {
+				break
+			}
+			var events []gpelements.EclipseEvent
+			if events, err = e.Eclipses(t0, t1, gpelements.EclipseOptions{}); err != nil {
+				break
+			}
+			for _, x := range events {
+				m := map[string]interface{}{
+					"Name":  e.Name,
+					"Id":    e.Id,
+					"Norad": e.NoradCatId,
+					"At":    x.At,
+					"From":  x.From,
+					"To":    x.To,
 				}
-			case "kvn":
-				s, err = e.MarshalKVN()
-			case "tle":
-				var l0, l1, l2 string
-				if l0, l1, l2, err = e.MarshalTLE(); err == nil {
-					s = fmt.Sprintf("%s\n%s\n%s", l0, l1, l2)
+				var js []byte
+				if js, err = json.Marshal(&m); err != nil {
+					break
 				}
-			case "xml":
-				es = append(es, e)
-			case "jsonarray":
-				es = append(es, e)
-			default:
-				return fmt.Errorf("unknown output representation '%s'", *emit)
+				fmt.Printf("%s\n", js)
 			}
-		case "prop":
-			err = Prop(&e, t0, t1, *propInterval, true)
 		case "sample":
 			var (
 				k = e.Name + "/" + e.Id + "/" + string(e.NoradCatId)
@@ -280,15 +469,14 @@ Subcommands:
 			}
 
 		case "orbit", "on-orbit":
-			t0, err := time.Parse(time.RFC3339Nano, *orbitFrom)
-			if err != nil {
-				return err
+			var t0, t1 time.Time
+			if t0, err = at(*orbitFrom, &e); err != nil {
+				break
 			}
-			t1, err := time.Parse(time.RFC3339Nano, *orbitTo)
-			if err != nil {
-				return err
+			if t1, err = at(*orbitTo, &e); err != nil {
+				break
 			}
-			err = Prop(&e, t0, t1, *orbitInterval, false)
+			err = Prop(&e, t0, t1, *orbitInterval, gpelements.TEME, nil, false)
 			if err == nil {
 				bs, err = json.Marshal(e)
 				if err == nil {
@@ -307,10 +495,10 @@ Subcommands:
 			} else {
 				e.UpdateName(id)
 			}
-			e.NoradCatId = gpelements.NoradCatId(id)
+			e.NoradCatId = gpelements.NoradCatId(id).Decode()
 			e.ElementSet = 0
-			if epoch != nil {
-				e.Epoch = epoch
+			if err = resetEpoch(*renameResetEpoch, &e); err != nil {
+				break
 			}
 
 			// Probably should emit in a high-precision format.
@@ -322,10 +510,9 @@ Subcommands:
 			if err = e.Walk(*minSteps, *maxSteps); err == nil {
 				if *incSet {
 					if err = e.IncSetNum(); err == nil {
-						if epoch != nil {
-							e.Epoch = epoch
-						}
-
+						err = resetEpoch(*walkResetEpoch, &e)
+					}
+					if err == nil {
 						// Probably should emit in a high-precision format.
 						var l0, l1, l2 string
 						l0, l1, l2, err = e.MarshalTLE()
@@ -338,10 +525,7 @@ Subcommands:
 		}
 
 		if err != nil {
-			if *tolerate {
-				log.Printf("at %d %v", i, err)
-				err = nil
-			}
+			err = fmt.Errorf("at %d (%s) %v", i, where(e), err)
 		}
 
 		i++
@@ -353,30 +537,24 @@ Subcommands:
 		return err
 	})
 
-	if err == nil {
-		var bs []byte
-		switch subcommand {
-		case "jsonarray":
-			bs, err = json.MarshalIndent(es, "", "  ")
-			if err == nil {
-				fmt.Printf("%s\n", bs)
-			}
+	if err == nil && out != nil {
+		err = out.Close()
+	}
 
-		case "xml":
-			list := gpelements.ElementsList{
-				Es: es,
-			}
-			bs, err := xml.Marshal(list)
-			if err == nil {
-				fmt.Printf("%s\n", bs)
-			}
+	if passesOut != nil {
+		if cerr := passesOut.Close(); err == nil {
+			err = cerr
 		}
 	}
 
+	if *tolerate {
+		log.Printf("%d element sets handled, %d failed", res.Decoded, res.Failed)
+	}
+
 	return err
 }
 
-func Prop(e *gpelements.Elements, from, to time.Time, interval time.Duration, print bool) error {
+func Prop(e *gpelements.Elements, from, to time.Time, interval time.Duration, frame gpelements.Frame, observer *gpelements.Observer, print bool) error {
 	o, err := e.SGP4()
 	if err != nil {
 		return err
@@ -396,6 +574,26 @@ func Prop(e *gpelements.Elements, from, to time.Time, interval time.Duration, pr
 			continue
 		}
 
+		var look *gpelements.Look
+		if observer != nil {
+			if look, err = observer.Look(s); err != nil {
+				return err
+			}
+		}
+
+		illumination, sunlight, err := s.Sunlight("")
+		if err != nil {
+			return err
+		}
+		beta, err := s.BetaAngle()
+		if err != nil {
+			return err
+		}
+
+		if s, err = s.InFrame(frame); err != nil {
+			return err
+		}
+
 		m := map[string]interface{}{
 			"Name":  e.Name,
 			"Id":    e.Id,
@@ -404,6 +602,13 @@ func Prop(e *gpelements.Elements, from, to time.Time, interval time.Duration, pr
 			"State": s,
 			"LLA":   lla,
 			"Age":   t.Sub(time.Time(*e.Epoch)).Seconds(),
+
+			"Illumination": illumination,
+			"Sunlight":     sunlight,
+			"Beta":         beta,
+		}
+		if look != nil {
+			m["Look"] = look
 		}
 		js, err := json.Marshal(&m)
 		if err != nil {
@@ -415,6 +620,14 @@ func Prop(e *gpelements.Elements, from, to time.Time, interval time.Duration, pr
 	return nil
 }
 
+// where describes where an element set came from.
+func where(e gpelements.Elements) string {
+	if e.Source == "" || e.Source == "-" {
+		return fmt.Sprintf("record %d", e.Record)
+	}
+	return fmt.Sprintf("%s record %d", e.Source, e.Record)
+}
+
 func Hash(s string) uint64 {
 	h := fnv.New64a()
 	h.Write([]byte(s))
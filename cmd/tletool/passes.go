@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/morphism/gpelements"
+)
+
+// PassRow is what the passes subcommand emits for each pass.
+type PassRow struct {
+	Name       string
+	NoradCatId gpelements.NoradCatId
+	Id         string
+	AOS        time.Time
+	AOSAz      float64
+	TCA        time.Time
+	TCAAz      float64
+	MaxEl      float64
+	LOS        time.Time
+	LOSAz      float64
+	Duration   float64
+	Quality    string
+}
+
+func NewPassRow(e *gpelements.Elements, p gpelements.Pass) PassRow {
+	return PassRow{
+		Name:       e.Name,
+		NoradCatId: e.NoradCatId,
+		Id:         e.Id,
+		AOS:        p.AOS.At,
+		AOSAz:      p.AOS.Az,
+		TCA:        p.TCA.At,
+		TCAAz:      p.TCA.Az,
+		MaxEl:      p.TCA.El,
+		LOS:        p.LOS.At,
+		LOSAz:      p.LOS.Az,
+		Duration:   p.Duration.Seconds(),
+		Quality:    p.Quality,
+	}
+}
+
+var passColumns = []string{
+	"NAME", "NORAD_CAT_ID", "ID",
+	"AOS", "AOS_AZ", "TCA", "TCA_AZ", "MAX_EL", "LOS", "LOS_AZ",
+	"DURATION", "QUALITY",
+}
+
+func (r PassRow) strings() []string {
+	var (
+		t = func(t time.Time) string {
+			return t.UTC().Format("2006-01-02T15:04:05.000Z")
+		}
+		f = func(x float64, prec int) string {
+			return strconv.FormatFloat(x, 'f', prec, 64)
+		}
+	)
+	return []string{
+		r.Name, string(r.NoradCatId), r.Id,
+		t(r.AOS), f(r.AOSAz, 1), t(r.TCA), f(r.TCAAz, 1), f(r.MaxEl, 1), t(r.LOS), f(r.LOSAz, 1),
+		f(r.Duration, 0), r.Quality,
+	}
+}
+
+// PassWriter writes PassRows as csv, csvh, json, or jsonarray.  The
+// other representations are only for element sets.
+type PassWriter struct {
+	emit string
+	w    io.Writer
+	csv  *csv.Writer
+	n    int
+
+	closed bool
+}
+
+func NewPassWriter(w io.Writer, emit string) (*PassWriter, error) {
+	pw := &PassWriter{
+		emit: emit,
+		w:    w,
+	}
+	switch emit {
+	case "csv", "csvh":
+		pw.csv = csv.NewWriter(w)
+	case "json", "jsonarray":
+	default:
+		return nil, fmt.Errorf("passes can't emit '%s' (want csv|csvh|json|jsonarray)", emit)
+	}
+	return pw, nil
+}
+
+func (pw *PassWriter) Write(r PassRow) error {
+	defer func() { pw.n++ }()
+
+	switch pw.emit {
+	case "csv", "csvh":
+		if pw.n == 0 && pw.emit == "csvh" {
+			if err := pw.csv.Write(passColumns); err != nil {
+				return err
+			}
+		}
+		if err := pw.csv.Write(r.strings()); err != nil {
+			return err
+		}
+		pw.csv.Flush()
+		return pw.csv.Error()
+	}
+
+	js, err := json.Marshal(&r)
+	if err != nil {
+		return err
+	}
+	sep := "\n"
+	if pw.emit == "jsonarray" {
+		sep = ","
+		if pw.n == 0 {
+			sep = "["
+		}
+	}
+	if pw.emit == "json" {
+		_, err = fmt.Fprintf(pw.w, "%s%s", js, sep)
+	} else {
+		_, err = fmt.Fprintf(pw.w, "%s%s", sep, js)
+	}
+	return err
+}
+
+// Close finishes the output.  Calling it again does nothing.
+func (pw *PassWriter) Close() error {
+	if pw.closed || pw.emit != "jsonarray" {
+		return nil
+	}
+	pw.closed = true
+	s := "]\n"
+	if pw.n == 0 {
+		s = "[]\n"
+	}
+	_, err := fmt.Fprint(pw.w, s)
+	return err
+}
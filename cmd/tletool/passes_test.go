@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPassRows() []PassRow {
+	aos := time.Date(2020, 12, 15, 6, 0, 0, 0, time.UTC)
+	return []PassRow{
+		{
+			Name:       "ISS (ZARYA)",
+			NoradCatId: "25544",
+			Id:         "1998-067A",
+			AOS:        aos,
+			AOSAz:      310.25,
+			TCA:        aos.Add(5 * time.Minute),
+			TCAAz:      220,
+			MaxEl:      45.5,
+			LOS:        aos.Add(10 * time.Minute),
+			LOSAz:      130,
+			Duration:   600,
+			Quality:    "good",
+		},
+		{
+			Name:       "ISS, AGAIN",
+			NoradCatId: "25544",
+			Id:         "1998-067A",
+			AOS:        aos.Add(time.Hour),
+			TCA:        aos.Add(time.Hour + time.Minute),
+			MaxEl:      10,
+			LOS:        aos.Add(time.Hour + 2*time.Minute),
+			Duration:   120,
+			Quality:    "short",
+		},
+	}
+}
+
+func writePasses(t *testing.T, emit string, rows []PassRow) string {
+	var buf bytes.Buffer
+	pw, err := NewPassWriter(&buf, emit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		if err = pw.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestPassWriterCSV(t *testing.T) {
+	row := "ISS (ZARYA),25544,1998-067A,2020-12-15T06:00:00.000Z,310.2,2020-12-15T06:05:00.000Z,220.0,45.5,2020-12-15T06:10:00.000Z,130.0,600,good\n"
+	again := `"ISS, AGAIN",25544,1998-067A,2020-12-15T07:00:00.000Z,0.0,2020-12-15T07:01:00.000Z,0.0,10.0,2020-12-15T07:02:00.000Z,0.0,120,short` + "\n"
+
+	if got := writePasses(t, "csv", testPassRows()); got != row+again {
+		t.Fatal(got)
+	}
+
+	header := strings.Join(passColumns, ",") + "\n"
+	if got := writePasses(t, "csvh", testPassRows()); got != header+row+again {
+		t.Fatal(got)
+	}
+}
+
+func TestPassWriterJSON(t *testing.T) {
+	rows := testPassRows()
+
+	got := writePasses(t, "json", rows)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != len(rows) {
+		t.Fatal(got)
+	}
+	for i, line := range lines {
+		var r PassRow
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r != rows[i] {
+			t.Fatalf("%d: %#v", i, r)
+		}
+	}
+
+	// Close writes the array's end (once).
+	got = writePasses(t, "jsonarray", rows)
+	if !strings.HasSuffix(got, "}]\n") {
+		t.Fatal(got)
+	}
+	var rs []PassRow
+	if err := json.Unmarshal([]byte(got), &rs); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != len(rows) || rs[0] != rows[0] || rs[1] != rows[1] {
+		t.Fatalf("%#v", rs)
+	}
+
+	if got = writePasses(t, "jsonarray", nil); got != "[]\n" {
+		t.Fatal(got)
+	}
+	if got = writePasses(t, "json", nil); got != "" {
+		t.Fatal(got)
+	}
+}
+
+func TestPassWriterEmit(t *testing.T) {
+	for _, emit := range []string{"tle", "kvn", "xml"} {
+		if _, err := NewPassWriter(&bytes.Buffer{}, emit); err == nil {
+			t.Fatal(emit)
+		}
+	}
+}
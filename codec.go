@@ -0,0 +1,244 @@
+package gpelements
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Codec is a representation of element sets.
+//
+// The codecs for this package's formats are registered by name:
+// "tle", "csv", "csvh" (CSV with a header), "json" (one JSON object
+// per line), "jsonarray", "kvn", and "xml".  Other packages can
+// register their own with RegisterCodec.
+type Codec interface {
+	// Name is how the codec is chosen (like "csv").
+	Name() string
+
+	// Sniff reports whether input that starts with the given bytes
+	// (usually MinBufferSize of them unless the input is shorter)
+	// looks like this representation.
+	Sniff(peek []byte) bool
+
+	// NewReader returns a reader for input in this
+	// representation.
+	NewReader(r *bufio.Reader, opts ParseOptions) ElementsReader
+
+	// NewWriter returns a writer for output in this
+	// representation.
+	NewWriter(w io.Writer, opts MarshalOptions) ElementsWriter
+}
+
+// ElementsReader reads element sets one at a time.
+type ElementsReader interface {
+	// Read returns the next element set or io.EOF.
+	//
+	// After a ParseError, Read can be called again for the next
+	// element set.  After an error that leaves the input
+	// unreadable, Read should return io.EOF.
+	Read() (*Elements, error)
+}
+
+// ElementsWriter writes element sets one at a time.
+type ElementsWriter interface {
+	// Write writes an element set, preceded by any header if
+	// it's the first one.
+	Write(e *Elements) error
+
+	// Close writes any footer and flushes buffered output.  It
+	// doesn't close the underlying io.Writer.
+	Close() error
+}
+
+// codec is a Codec made of functions.
+type codec struct {
+	name      string
+	sniff     func(peek []byte) bool
+	newReader func(r *bufio.Reader, opts ParseOptions) ElementsReader
+	newWriter func(w io.Writer, opts MarshalOptions) ElementsWriter
+}
+
+func (c *codec) Name() string {
+	return c.name
+}
+
+func (c *codec) Sniff(peek []byte) bool {
+	if c.sniff == nil {
+		return false
+	}
+	return c.sniff(peek)
+}
+
+func (c *codec) NewReader(r *bufio.Reader, opts ParseOptions) ElementsReader {
+	return c.newReader(r, opts)
+}
+
+func (c *codec) NewWriter(w io.Writer, opts MarshalOptions) ElementsWriter {
+	return c.newWriter(w, opts)
+}
+
+var (
+	codecsLock sync.RWMutex
+	codecs     []Codec
+)
+
+// RegisterCodec adds a codec, which replaces any existing codec with
+// the same name.  SniffCodec tries codecs in the order they were
+// first registered.
+func RegisterCodec(c Codec) {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+
+	for i, have := range codecs {
+		if have.Name() == c.Name() {
+			codecs[i] = c
+			return
+		}
+	}
+	codecs = append(codecs, c)
+}
+
+// Codecs returns the registered codecs.
+func Codecs() []Codec {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+
+	return append([]Codec(nil), codecs...)
+}
+
+// CodecNames returns the names of the registered codecs.
+func CodecNames() []string {
+	cs := Codecs()
+	names := make([]string, len(cs))
+	for i, c := range cs {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// LookupCodec returns the codec with the given name.
+func LookupCodec(name string) (Codec, bool) {
+	for _, c := range Codecs() {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// SniffCodec returns the first codec that claims the input that
+// starts with the given bytes or nil if none do.
+func SniffCodec(peek []byte) Codec {
+	for _, c := range Codecs() {
+		if c.Sniff(peek) {
+			return c
+		}
+	}
+	return nil
+}
+
+// firstByteIs makes a sniffer that checks the first non-space byte.
+func firstByteIs(b byte) func(peek []byte) bool {
+	return func(peek []byte) bool {
+		peek = bytes.TrimLeft(peek, " \t\r\n")
+		return 0 < len(peek) && peek[0] == b
+	}
+}
+
+func init() {
+	for _, c := range []*codec{
+		{
+			name:  "xml",
+			sniff: firstByteIs('<'),
+			newReader: func(r *bufio.Reader, opts ParseOptions) ElementsReader {
+				return newXMLReader(r)
+			},
+			newWriter: func(w io.Writer, opts MarshalOptions) ElementsWriter {
+				return &xmlWriter{w: w}
+			},
+		},
+		{
+			name:  "jsonarray",
+			sniff: firstByteIs('['),
+			newReader: func(r *bufio.Reader, opts ParseOptions) ElementsReader {
+				return newJSONArrayReader(r)
+			},
+			newWriter: func(w io.Writer, opts MarshalOptions) ElementsWriter {
+				return &jsonWriter{w: w, array: true}
+			},
+		},
+		{
+			name:  "json",
+			sniff: firstByteIs('{'),
+			newReader: func(r *bufio.Reader, opts ParseOptions) ElementsReader {
+				return &jsonLinesReader{r: r}
+			},
+			newWriter: func(w io.Writer, opts MarshalOptions) ElementsWriter {
+				return &jsonWriter{w: w}
+			},
+		},
+		{
+			name:  "kvn",
+			sniff: MaybeKVN,
+			newReader: func(r *bufio.Reader, opts ParseOptions) ElementsReader {
+				return &kvnReader{r: r, opts: opts}
+			},
+			newWriter: func(w io.Writer, opts MarshalOptions) ElementsWriter {
+				return &kvnWriter{w: w}
+			},
+		},
+		{
+			name:  "tle",
+			sniff: sniffTLE,
+			newReader: func(r *bufio.Reader, opts ParseOptions) ElementsReader {
+				return &tleElementsReader{
+					r:    NewTLEReader(r),
+					opts: opts,
+				}
+			},
+			newWriter: func(w io.Writer, opts MarshalOptions) ElementsWriter {
+				return &tleWriter{w: w, opts: opts}
+			},
+		},
+		{
+			name:  "csv",
+			sniff: MaybeCSV,
+			newReader: func(r *bufio.Reader, opts ParseOptions) ElementsReader {
+				return NewCSVReader(r)
+			},
+			newWriter: func(w io.Writer, opts MarshalOptions) ElementsWriter {
+				return NewCSVWriter(w, nil, false)
+			},
+		},
+		{
+			// Input with a header is just "csv".
+			name: "csvh",
+			newReader: func(r *bufio.Reader, opts ParseOptions) ElementsReader {
+				return NewCSVReader(r)
+			},
+			newWriter: func(w io.Writer, opts MarshalOptions) ElementsWriter {
+				return NewCSVWriter(w, nil, true)
+			},
+		},
+	} {
+		RegisterCodec(c)
+	}
+}
+
+// doElements calls f on each element set from the reader.
+func doElements(r ElementsReader, f func(Elements) error) error {
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = f(*e); err != nil {
+			return err
+		}
+	}
+}
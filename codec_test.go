@@ -0,0 +1,137 @@
+package gpelements
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	in, err := os.Open("data/test.tle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close() // Ignore error.
+
+	var es []Elements
+	if err = DoTLE(bufio.NewReader(in), func(e Elements) error {
+		es = append(es, e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"tle", "csv", "csvh", "json", "jsonarray", "kvn", "xml"} {
+		c, have := LookupCodec(name)
+		if !have {
+			t.Fatal(name)
+		}
+		var (
+			buf bytes.Buffer
+			w   = c.NewWriter(&buf, MarshalOptions{})
+		)
+		for i := range es {
+			if err := w.Write(&es[i]); err != nil {
+				t.Fatalf("%s: %s", c.Name(), err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: %s", c.Name(), err)
+		}
+
+		peek := buf.Bytes()
+		if MinBufferSize < len(peek) {
+			peek = peek[0:MinBufferSize]
+		}
+		if s := SniffCodec(peek); s == nil || (s.Name() != c.Name() && c.Name() != "csvh") {
+			t.Fatalf("%s sniffed as %v", c.Name(), s)
+		}
+
+		r := c.NewReader(bufio.NewReader(&buf), DefaultParseOptions)
+		n := 0
+		for {
+			e, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %s", c.Name(), err)
+			}
+			if e.NoradCatId != es[n].NoradCatId {
+				t.Fatalf("%s: %s != %s", c.Name(), e.NoradCatId, es[n].NoradCatId)
+			}
+			n++
+		}
+		if n != len(es) {
+			t.Fatalf("%s: %d != %d", c.Name(), n, len(es))
+		}
+	}
+}
+
+// namesCodec is a codec for just the names of element sets.
+type namesCodec struct{}
+
+func (c namesCodec) Name() string {
+	return "names"
+}
+
+func (c namesCodec) Sniff(peek []byte) bool {
+	return bytes.HasPrefix(peek, []byte("NAMES\n"))
+}
+
+func (c namesCodec) NewReader(r *bufio.Reader, opts ParseOptions) ElementsReader {
+	return nil
+}
+
+func (c namesCodec) NewWriter(w io.Writer, opts MarshalOptions) ElementsWriter {
+	return &namesWriter{w: w}
+}
+
+type namesWriter struct {
+	w io.Writer
+}
+
+func (w *namesWriter) Write(e *Elements) error {
+	_, err := io.WriteString(w.w, e.Name+"\n")
+	return err
+}
+
+func (w *namesWriter) Close() error {
+	return nil
+}
+
+func TestRegisterCodec(t *testing.T) {
+	RegisterCodec(namesCodec{})
+
+	if c := SniffCodec([]byte("NAMES\nISS\n")); c == nil || c.Name() != "names" {
+		t.Fatal(c)
+	}
+
+	e := NewElements()
+	e.Name = "ISS"
+	s, err := e.Marshal("names")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "ISS\n" {
+		t.Fatal(s)
+	}
+
+	if _, err := e.Marshal("nope"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMarshalXMLIsOneOMM(t *testing.T) {
+	e := testElements(t)
+	s, err := e.Marshal("xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(s, "<omm") || strings.Contains(s, "<ndm>") {
+		t.Fatal(s)
+	}
+}
@@ -2,46 +2,56 @@ package gpelements
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
-	"time"
 )
 
 const (
+	// CSVHeader is the default CSV layout, which is Celestrak's.
 	CSVHeader = "OBJECT_NAME,OBJECT_ID,EPOCH,MEAN_MOTION,ECCENTRICITY,INCLINATION,RA_OF_ASC_NODE,ARG_OF_PERICENTER,MEAN_ANOMALY,EPHEMERIS_TYPE,CLASSIFICATION_TYPE,NORAD_CAT_ID,ELEMENT_SET_NO,REV_AT_EPOCH,BSTAR,MEAN_MOTION_DOT,MEAN_MOTION_DDOT"
 )
 
 var (
 	CSVTimeFormat = "2006-01-02T15:04:05.999999999"
+
+	// CSVColumns is CSVHeader as a list of OMM keywords.
+	CSVColumns = strings.Split(CSVHeader, ",")
+
+	// csvQuoted are the columns that the default layout always
+	// quotes, as Celestrak does.
+	csvQuoted = map[string]bool{
+		"OBJECT_NAME":         true,
+		"OBJECT_ID":           true,
+		"EPOCH":               true,
+		"CLASSIFICATION_TYPE": true,
+		"NORAD_CAT_ID":        true,
+	}
 )
 
+// MarshalCSV renders the element set with CSVColumns without a
+// trailing newline.
 func (e *Elements) MarshalCSV() (string, error) {
-	f := "%q,%q,%q,%g,%g,%g,%g,%g,%g,%d,%q,%q,%d,%g,%g,%g,%g"
-
-	return fmt.Sprintf(f,
-		e.Name,
-		e.Id,
-		e.Epoch.Format(CSVTimeFormat),
-		e.MeanMotion,
-		e.Eccentricity,
-		e.Inclination,
-		e.RightAscension,
-		e.ArgOfPericenter,
-		e.MeanAnomaly,
-		e.EphemerisType,
-		e.ClassificationType,
-		e.NoradCatId,
-		e.ElementSet,
-		e.RevAtEpoch,
-		e.BStar,
-		e.MeanMotionDot,
-		e.MeanMotionDDot,
-	), nil
+	var (
+		buf bytes.Buffer
+		w   = NewCSVWriter(&buf, nil, false)
+	)
+	if err := w.Write(e); err != nil {
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(buf.String(), "\r\n"), nil
 }
 
 // QuoteString will make sure the given line has double-quoted values
 // where we (think) we want them.
+//
+// Deprecated: CSVReader uses encoding/csv, which doesn't need help.
 func QuoteStrings(line string) string {
 	ss := strings.Split(line, ",")
 	which := []bool{
@@ -60,67 +70,339 @@ func QuoteStrings(line string) string {
 	return strings.Join(ss, ",")
 }
 
+// ParseCSV parses a line with CSVColumns.
+//
+// The returned int is the number of values in the line.
 func ParseCSV(line string) (*Elements, int, error) {
+	values, err := splitCSV(line)
+	if err != nil {
+		return nil, 0, recordError(err, "csv", 0, 0)
+	}
+	e, err := csvElements(CSVColumns, values)
+	return e, len(values), err
+}
+
+func splitCSV(line string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(line))
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	return r.Read()
+}
+
+// csvElements makes Elements from values in the given columns.
+//
+// Unknown columns go to Extras, and empty values are ignored except
+// that every keyword in ommRequired must have one.
+func csvElements(columns, values []string) (*Elements, error) {
+	if len(columns) != len(values) {
+		return nil, &ParseError{
+			Format: "csv",
+			Err:    fmt.Errorf("%d values for %d columns", len(values), len(columns)),
+		}
+	}
 
-	line = QuoteStrings(line)
 	var (
-		e  = &Elements{}
-		ts string
-		f  = `%q,%q,%q,%f,%f,%f,%f,%f,%f,%d,%q,%q,%d,%f,%f,%f,%f`
+		e    = NewElements()
+		have = make(map[string]bool, len(columns))
 	)
+	for i, s := range values {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		k := columns[i]
+		field, known := lookupField(k)
+		if !known {
+			e.SetExtra(k, s)
+			continue
+		}
+		ok, err := field.setValue(e, s)
+		if err != nil {
+			return nil, csvError(k, s, err)
+		}
+		have[k] = ok
+	}
 
-	// ToDo: Include Originator, CreationDate?
-
-	n, err := fmt.Sscanf(line, f,
-		&e.Name,
-		&e.Id,
-		&ts,
-		&e.MeanMotion,
-		&e.Eccentricity,
-		&e.Inclination,
-		&e.RightAscension,
-		&e.ArgOfPericenter,
-		&e.MeanAnomaly,
-		&e.EphemerisType,
-		&e.ClassificationType,
-		&e.NoradCatId,
-		&e.ElementSet,
-		&e.RevAtEpoch,
-		&e.BStar,
-		&e.MeanMotionDot,
-		&e.MeanMotionDDot,
-	)
+	if k, missing := missingRequired(have); missing {
+		return nil, csvError(k, "", fmt.Errorf("missing value"))
+	}
 
-	if err != nil {
-		return nil, n, err
+	if err := e.UseUTC(); err != nil {
+		return nil, csvError("TIME_SYSTEM", e.TimeSystem, err)
 	}
 
-	t, err := time.Parse(CSVTimeFormat, ts)
-	if err != nil {
-		return nil, n, err
+	return e, nil
+}
 
+func csvError(k, s string, err error) *ParseError {
+	return &ParseError{
+		Format: "csv",
+		Field:  k,
+		Text:   s,
+		Err:    err,
 	}
-	t0 := Time(t)
-	e.Epoch = &t0
+}
 
-	return e, n, nil
+// CSVReader reads element sets from CSV input.
+//
+// If the first line is a header, columns are mapped by the OMM
+// keywords in that header.  Otherwise the columns are CSVColumns.
+// Each record must be on a single line.
+type CSVReader struct {
+	r       *bufio.Reader
+	columns []string
+	line    int
+	record  int
 
+	// ended is true after an error that spoils the rest of the
+	// input.
+	ended bool
 }
 
+func NewCSVReader(r *bufio.Reader) *CSVReader {
+	return &CSVReader{
+		r: r,
+	}
+}
+
+// Columns returns the columns in use, which are only known after the
+// first Read.
+func (r *CSVReader) Columns() []string {
+	return r.columns
+}
+
+// Read returns the next element set or io.EOF.
+func (r *CSVReader) Read() (*Elements, error) {
+	if r.ended {
+		return nil, io.EOF
+	}
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" && err == io.EOF {
+			return nil, io.EOF
+		}
+		r.line++
+
+		line = strings.TrimRight(line, "\n\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		values, err := splitCSV(line)
+		if err != nil {
+			r.record++
+			return nil, recordError(err, "csv", r.record, r.line)
+		}
+
+		if r.columns == nil {
+			if isCSVHeader(values) {
+				if err = r.useHeader(values); err != nil {
+					r.ended = true
+					err = wrapErrf(err, "header")
+					return nil, recordError(err, "csv", 0, r.line)
+				}
+				continue
+			}
+			r.columns = CSVColumns
+		}
+
+		r.record++
+		e, err := csvElements(r.columns, values)
+		if err != nil {
+			return nil, recordError(err, "csv", r.record, r.line)
+		}
+		return e, nil
+	}
+}
+
+func (r *CSVReader) useHeader(values []string) error {
+	columns := make([]string, len(values))
+	have := make(map[string]bool, len(values))
+	for i, s := range values {
+		columns[i] = strings.TrimSpace(s)
+		have[columns[i]] = true
+	}
+	for _, k := range ommRequired {
+		if !have[k] {
+			return fmt.Errorf("missing required column %s", k)
+		}
+	}
+	r.columns = columns
+	return nil
+}
+
+// isCSVHeader guesses that a line is a header if it has at least a
+// couple of OMM keywords.
+func isCSVHeader(values []string) bool {
+	n := 0
+	for _, s := range values {
+		if _, have := lookupField(strings.TrimSpace(s)); have {
+			n++
+		}
+	}
+	return 2 <= n
+}
+
+// DoCSV calls f on each element set read with a CSVReader.
+func DoCSV(r *bufio.Reader, f func(Elements) error) error {
+	return doElements(NewCSVReader(r), f)
+}
+
+// CSVWriter writes element sets as CSV with the given columns, which
+// are OMM keywords or the keys of Extras.
+type CSVWriter struct {
+	w       *bufio.Writer
+	columns []string
+	header  bool
+
+	// quoted, if not nil, are the columns whose values are always
+	// quoted.
+	quoted map[string]bool
+
+	// extras, if not nil, are the Extras keys that the header got
+	// from the first element set.
+	extras map[string]bool
+}
+
+// NewCSVWriter makes a CSVWriter that will write the given columns,
+// optionally preceded by a header line.
+//
+// If columns is nil, the columns are CSVColumns followed (if there's
+// a header) by the keys of the first element set's Extras.  Then
+// Write returns an error for a later element set with an Extras key
+// that isn't in the header.  This default layout also always quotes
+// the string values OBJECT_NAME, OBJECT_ID, EPOCH,
+// CLASSIFICATION_TYPE, and NORAD_CAT_ID.  Otherwise values are only
+// quoted when they need to be.
+func NewCSVWriter(w io.Writer, columns []string, header bool) *CSVWriter {
+	return &CSVWriter{
+		w:       bufio.NewWriter(w),
+		columns: columns,
+		header:  header,
+	}
+}
+
+func (w *CSVWriter) Write(e *Elements) error {
+	if w.columns == nil {
+		w.columns = CSVColumns
+		w.quoted = csvQuoted
+		if w.header && 0 < len(e.Extras) {
+			w.columns = append([]string(nil), CSVColumns...)
+			w.extras = make(map[string]bool, len(e.Extras))
+			for _, x := range e.Extras {
+				w.columns = append(w.columns, x.Key)
+				w.extras[x.Key] = true
+			}
+		}
+	}
+
+	if w.extras != nil {
+		for _, x := range e.Extras {
+			if !w.extras[x.Key] {
+				return fmt.Errorf("extra field %s isn't in the CSV header (give the columns or strip extras)", x.Key)
+			}
+		}
+	}
+
+	if w.header {
+		if err := w.writeRecord(w.columns, nil); err != nil {
+			return err
+		}
+		w.header = false
+	}
+
+	values := make([]string, len(w.columns))
+	for i, k := range w.columns {
+		field, have := lookupField(k)
+		if !have {
+			for _, x := range e.Extras {
+				if x.Key == k {
+					values[i] = x.Text()
+					break
+				}
+			}
+			continue
+		}
+		if field.time != nil {
+			if t := *field.time(e); t != nil {
+				values[i] = t.Format(CSVTimeFormat)
+			}
+			continue
+		}
+		values[i] = field.get(e)
+	}
+
+	return w.writeRecord(values, w.quoted)
+}
+
+// writeRecord writes a line of values, quoting those in the given
+// columns and any others that need it.
+func (w *CSVWriter) writeRecord(values []string, quoted map[string]bool) error {
+	for i, s := range values {
+		if 0 < i {
+			if err := w.w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if quoted[w.columns[i]] || csvNeedsQuotes(s) {
+			s = `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+		}
+		if _, err := w.w.WriteString(s); err != nil {
+			return err
+		}
+	}
+	return w.w.WriteByte('\n')
+}
+
+// csvNeedsQuotes reports whether encoding/csv would quote the value.
+func csvNeedsQuotes(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s == `\.` || strings.ContainsAny(s, ",\"\r\n") {
+		return true
+	}
+	return s[0] == ' ' || s[0] == '\t'
+}
+
+// Flush writes any buffered data.
+func (w *CSVWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Close is Flush.
+func (w *CSVWriter) Close() error {
+	return w.Flush()
+}
+
+// DoLines calls f on each non-empty line.  A ParseError from f gets
+// the line number.  Any other error from f is wrapped with the line
+// number, so errors.Is and errors.As still find it.
 func DoLines(r *bufio.Reader, f func(s string) error) error {
 
 	i := 0
 	for {
 		line, err := r.ReadString('\n')
 		line = strings.TrimRight(line, "\n\r")
+		i++
 		if 0 < len(line) {
 			if err := f(line); err != nil {
-				return fmt.Errorf("error parsing line %d: %s", i, err)
+				var pe *ParseError
+				if errors.As(err, &pe) {
+					return recordError(err, "", 0, i)
+				}
+				return fmt.Errorf("line %d: %w", i, err)
 			}
 		}
 		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,181 @@
+package gpelements
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVFile(t *testing.T) {
+	filename := "data/test.csv"
+
+	in, err := os.Open(filename)
+	if err != nil {
+		t.Skipf("Couldn't open %s: %s", filename, err)
+	}
+	defer in.Close() // Ignore error.
+
+	n := 0
+	err = DoCSV(bufio.NewReader(in), func(e Elements) error {
+		if e.Epoch == nil {
+			t.Fatalf("no epoch for %s", e.Name)
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 76 {
+		t.Fatal(n)
+	}
+}
+
+func TestCSVHeader(t *testing.T) {
+	// Columns in a different order, an unknown column, some
+	// missing optional columns, and a comma in a name.
+	in := `NORAD_CAT_ID,EPOCH,MEAN_MOTION,ECCENTRICITY,INCLINATION,RA_OF_ASC_NODE,ARG_OF_PERICENTER,MEAN_ANOMALY,RCS_SIZE,OBJECT_NAME
+25544,2020-12-15T05:59:44.491200,15.49187914,.0001646,51.6439,172.5713,123.4001,50.7130,LARGE,"ISS (ZARYA), AKA ISS"
+`
+	r := NewCSVReader(bufio.NewReader(strings.NewReader(in)))
+	e, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "ISS (ZARYA), AKA ISS" {
+		t.Fatal(e.Name)
+	}
+	if e.NoradCatId != "25544" {
+		t.Fatal(e.NoradCatId)
+	}
+	if e.Eccentricity != 0.0001646 {
+		t.Fatal(e.Eccentricity)
+	}
+	if v, _ := e.GetExtra("RCS_SIZE"); v != "LARGE" {
+		t.Fatal(v)
+	}
+	if _, err = r.Read(); err != io.EOF {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, []string{"OBJECT_NAME", "NORAD_CAT_ID"}, true)
+	if err = w.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "OBJECT_NAME,NORAD_CAT_ID\n\"ISS (ZARYA), AKA ISS\",25544\n"; got != want {
+		t.Fatal(got)
+	}
+}
+
+func TestCSVMissingRequired(t *testing.T) {
+	in := "OBJECT_NAME,NORAD_CAT_ID\nISS,25544\n"
+	r := NewCSVReader(bufio.NewReader(strings.NewReader(in)))
+	if _, err := r.Read(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestCSVShortOrEmpty(t *testing.T) {
+	header := "NORAD_CAT_ID,EPOCH,MEAN_MOTION,ECCENTRICITY,INCLINATION,RA_OF_ASC_NODE,ARG_OF_PERICENTER,MEAN_ANOMALY\n"
+	for _, c := range []struct {
+		row, field string
+	}{
+		{"25544,,15.49187914,.0001646,51.6439,172.5713,123.4001,50.7130", "EPOCH"},
+		{"25544,null,15.49187914,.0001646,51.6439,172.5713,123.4001,50.7130", "EPOCH"},
+		{"25544,2020-12-15T05:59:44.491200,15.49187914,.0001646,51.6439,172.5713,,50.7130", "ARG_OF_PERICENTER"},
+		{"25544,2020-12-15T05:59:44.491200,15.49187914,.0001646,51.6439", ""},
+	} {
+		r := NewCSVReader(bufio.NewReader(strings.NewReader(header + c.row + "\n")))
+		_, err := r.Read()
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Field != c.field || pe.Line != 2 {
+			t.Fatalf("%s: %v", c.row, err)
+		}
+	}
+
+	if _, _, err := ParseCSV(`"ISS (ZARYA)","1998-067A",2020-12-15T05:59:44.491200`); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestCSVExtras(t *testing.T) {
+	e := NewElements()
+	e.Epoch = NewTime(time.Date(2020, 12, 15, 0, 0, 0, 0, time.UTC))
+	e.SetExtra("RCS_SIZE", "LARGE")
+
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, nil, true)
+	if err := w.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewCSVReader(bufio.NewReader(&buf))
+	check, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := check.GetExtra("RCS_SIZE"); v != "LARGE" {
+		t.Fatal(v)
+	}
+}
+
+func TestCSVExtrasNotInHeader(t *testing.T) {
+	e := NewElements()
+	e.Epoch = NewTime(time.Date(2020, 12, 15, 0, 0, 0, 0, time.UTC))
+	e.SetExtra("RCS_SIZE", "LARGE")
+
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, nil, true)
+	if err := w.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	e.SetExtra("COUNTRY_CODE", "US")
+	if err := w.Write(e); err == nil {
+		t.Fatal("should have complained")
+	}
+
+	// Without a header, Extras aren't written at all.
+	w = NewCSVWriter(&buf, nil, false)
+	if err := w.Write(e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCSVQuoting(t *testing.T) {
+	e := testElements(t)
+	e.Name = `ISS "ZARYA"`
+
+	s, err := e.MarshalCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(s, `"ISS ""ZARYA""","1998-067A","2020-09-18T16:13:57.999936",`) {
+		t.Fatal(s)
+	}
+	if !strings.Contains(s, `,"U","25544",`) {
+		t.Fatal(s)
+	}
+
+	check, _, err := ParseCSV(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Name != e.Name {
+		t.Fatal(check.Name)
+	}
+}
@@ -2,15 +2,25 @@ package gpelements
 
 import (
 	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
+// Marshal renders the element set by itself.  For "xml", that's a
+// lone <omm> element, not an <ndm> document.  Another registered
+// codec's writer renders just this element set.
 func (e *Elements) Marshal(how string) (string, error) {
 	switch how {
 	case "csv":
@@ -35,9 +45,23 @@ func (e *Elements) Marshal(how string) (string, error) {
 			return "", err
 		}
 		return l0 + "\n" + l1 + "\n" + l2 + "\n", nil
-	default:
+	}
+
+	c, have := LookupCodec(how)
+	if !have {
 		return "", fmt.Errorf("unknown marshal representation '%s'", how)
 	}
+	var (
+		buf bytes.Buffer
+		w   = c.NewWriter(&buf, DefaultMarshalOptions)
+	)
+	if err := w.Write(e); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
 
 func MaybeCSV(bs []byte) bool {
@@ -50,103 +74,331 @@ func MaybeCSV(bs []byte) bool {
 	return 4 < count // !
 }
 
+// MaybeKVN reports whether the first line that isn't blank or a
+// COMMENT starts an OMM in KVN format.
 func MaybeKVN(bs []byte) bool {
 	magic := "CCSDS_OMM_VERS"
-	if len(bs) < len(magic) {
-		return false
+	for _, line := range strings.Split(string(bs), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isKVNComment(line) {
+			continue
+		}
+		return strings.HasPrefix(line, magic)
 	}
-	return magic == string(bs[0:len(magic)])
+	return false
+}
+
+const (
+	MinBufferSize = 128
+
+	// DefaultBufferSize is the buffer size when DecodeOptions
+	// don't say.
+	DefaultBufferSize = 4096
+)
+
+// DecodeOptions control decoding.
+type DecodeOptions struct {
+	// Format is the name of the input's codec.  If it's empty,
+	// the codec is found by SniffCodec, and input that no codec
+	// claims is read as TLEs.
+	Format string
+
+	// BufSize is the size of the input buffer, which is at least
+	// MinBufferSize.
+	BufSize int
+
+	Parse ParseOptions
+
+	// Source is the name of the input (like a filename), which is
+	// given to each element set and ParseError.
+	Source string
+
+	// OnError says what DoContext does about an element set that
+	// can't be decoded or that the callback fails on.
+	OnError ErrorPolicy
+
+	// MaxErrors is the most errors that a DoResult keeps.  Zero
+	// means DefaultMaxErrors.
+	MaxErrors int
 }
 
-const MinBufferSize = 128
+// ErrorPolicy says what to do about errors.
+type ErrorPolicy int
+
+const (
+	// StopOnError stops at the first error, which is returned.
+	StopOnError ErrorPolicy = iota
+
+	// SkipAndLog logs the error and continues with the next
+	// element set.
+	SkipAndLog
 
+	// SkipAndCollect continues with the next element set after
+	// adding the error to the DoResult.
+	SkipAndCollect
+)
+
+// DefaultMaxErrors is the most errors that a DoResult keeps when
+// DecodeOptions don't say.
+const DefaultMaxErrors = 100
+
+// DoResult summarizes what DoContext did.
+type DoResult struct {
+	// Decoded is the number of element sets that were decoded
+	// and handled by the callback without error.
+	Decoded int
+
+	// Failed is the number of element sets that couldn't be
+	// decoded or that the callback failed on.  It doesn't count
+	// inputs that couldn't be read at all (like a file that
+	// can't be opened or a glob without matches), which only
+	// appear in Errors.
+	Failed int
+
+	// Errors are the first errors (up to MaxErrors) that were
+	// skipped or that stopped the work.
+	Errors []error
+}
+
+// problem records an error and returns it if the policy is to stop.
+func (r *DoResult) problem(opts DecodeOptions, err error) error {
+	max := opts.MaxErrors
+	if max == 0 {
+		max = DefaultMaxErrors
+	}
+	if len(r.Errors) < max {
+		r.Errors = append(r.Errors, err)
+	}
+
+	switch opts.OnError {
+	case SkipAndLog:
+		log.Printf("skipping: %v", err)
+	case SkipAndCollect:
+	default:
+		return err
+	}
+	return nil
+}
+
+// Do is DoWith using the given buffer size and DefaultParseOptions.
 func Do(in io.Reader, bufSize int, f func(Elements) error) error {
-	bin := bufio.NewReaderSize(in, bufSize)
-	peek, err := bin.Peek(MinBufferSize)
+	opts := DecodeOptions{
+		BufSize: bufSize,
+		Parse:   DefaultParseOptions,
+	}
+	return DoWith(in, opts, f)
+}
+
+// DoWith is DoContext without a context or a DoResult.
+func DoWith(in io.Reader, opts DecodeOptions, f func(Elements) error) error {
+	_, err := DoContext(context.Background(), in, opts, f)
+	return err
+}
+
+// DoContext calls f on each element set in the input until the input
+// ends, the context is done, or there's an error that opts.OnError
+// says to stop at.
+//
+// Input compressed with gzip or bzip2 is decompressed first.
+func DoContext(ctx context.Context, in io.Reader, opts DecodeOptions, f func(Elements) error) (*DoResult, error) {
+	var res DoResult
+	err := doContext(ctx, in, opts, f, &res)
+	return &res, err
+}
+
+func doContext(ctx context.Context, in io.Reader, opts DecodeOptions, f func(Elements) error, res *DoResult) error {
+	r, err := newElementsReader(in, opts)
 	if err != nil {
-		return err
+		return res.problem(opts, err)
 	}
 
-	switch peek[0] {
-	case '[', '<': // Just read in the whole thing.
-		slurp := func() ([]byte, error) {
-			return ioutil.ReadAll(bin)
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		e, err := r.Read()
+		if err == io.EOF {
+			return nil
 		}
+		if err == nil {
+			err = f(*e)
+		}
+		if err != nil {
+			res.Failed++
+			if err = res.problem(opts, err); err != nil {
+				return err
+			}
+			continue
+		}
+		res.Decoded++
+	}
+}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+)
+
+// newElementsReader decompresses the input as needed and finds its
+// codec.
+func newElementsReader(in io.Reader, opts DecodeOptions) (ElementsReader, error) {
+	r, err := newCodecReader(in, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceReader{
+		r:      r,
+		source: opts.Source,
+	}, nil
+}
 
-		var es []Elements
+func newCodecReader(in io.Reader, opts DecodeOptions) (ElementsReader, error) {
+	bufSize := opts.BufSize
+	switch {
+	case bufSize == 0:
+		bufSize = DefaultBufferSize
+	case bufSize < MinBufferSize:
+		bufSize = MinBufferSize
+	}
 
-		bs, err := slurp()
+	bin := bufio.NewReaderSize(in, bufSize)
+	peek, err := bin.Peek(len(bzip2Magic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(peek, gzipMagic):
+		z, err := gzip.NewReader(bin)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		switch peek[0] {
-		case '[':
-			log.Printf("Detected JSON array input")
+		bin = bufio.NewReaderSize(z, bufSize)
+	case bytes.HasPrefix(peek, bzip2Magic):
+		bin = bufio.NewReaderSize(bzip2.NewReader(bin), bufSize)
+	}
 
-			bs, err := slurp()
-			if err != nil {
-				return err
-			}
+	if opts.Format != "" {
+		c, have := LookupCodec(opts.Format)
+		if !have {
+			return nil, fmt.Errorf("unknown input representation '%s'", opts.Format)
+		}
+		return c.NewReader(bin, opts.Parse), nil
+	}
+
+	if peek, err = bin.Peek(MinBufferSize); err != nil && err != io.EOF {
+		return nil, err
+	}
+	c := SniffCodec(peek)
+	if c == nil {
+		c, _ = LookupCodec("tle")
+	}
+	if opts.Source == "" {
+		log.Printf("Detected %s input", c.Name())
+	} else {
+		log.Printf("Detected %s input in %s", c.Name(), opts.Source)
+	}
 
-			bs = []byte(DestringNumbers(string(bs)))
-			err = json.Unmarshal(bs, &es)
+	return c.NewReader(bin, opts.Parse), nil
+}
 
-		case '<':
-			log.Printf("Detected XML input")
+// sourceReader gives element sets their Source and Record and
+// ParseErrors their Source.
+//
+// Since an error that isn't a ParseError probably means the input
+// can't be read, the input ends after one, which is just prefixed
+// with the source.
+type sourceReader struct {
+	r      ElementsReader
+	source string
+	record int
+	ended  bool
+}
 
-			list := ElementsList{}
-			err = xml.Unmarshal(bs, &list)
-			es = list.Es // Hopefully
+func (r *sourceReader) Read() (*Elements, error) {
+	if r.ended {
+		return nil, io.EOF
+	}
+	e, err := r.r.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	r.record++
+	if err != nil {
+		var pe *ParseError
+		switch {
+		case !errors.As(err, &pe):
+			r.ended = true
+			if r.source != "" {
+				err = fmt.Errorf("%s: %w", r.source, err)
+			}
+		case r.source != "":
+			pe := recordError(err, "", 0, 0)
+			pe.Source = r.source
+			err = pe
 		}
+		return nil, err
+	}
+	e.Source = r.source
+	e.Record = r.record
+	return e, nil
+}
 
-		for _, e := range es {
-			if err = f(e); err != nil {
-				// ToDo: Consider toleration.
-				return err
+// DoFiles is DoFilesContext without a context or a DoResult.
+func DoFiles(names []string, opts DecodeOptions, f func(Elements) error) error {
+	_, err := DoFilesContext(context.Background(), names, opts, f)
+	return err
+}
+
+// DoFilesContext calls f on each element set in the named files,
+// which are read in the given order.  A name can be a glob pattern,
+// whose matches are read in sorted order, or "-" for the standard
+// input.
+//
+// Each file's representation is found separately (unless opts says
+// what it is), and opts.Source is each file's name.  A file that
+// can't be opened is an error that opts.OnError applies to.
+func DoFilesContext(ctx context.Context, names []string, opts DecodeOptions, f func(Elements) error) (*DoResult, error) {
+	var (
+		res       DoResult
+		filenames []string
+	)
+	for _, name := range names {
+		if name == "-" {
+			filenames = append(filenames, name)
+			continue
+		}
+		matches, err := filepath.Glob(name)
+		if err == nil && matches == nil {
+			err = fmt.Errorf("no matching files")
+		}
+		if err != nil {
+			if err = res.problem(opts, wrapErrf(err, "%s", name)); err != nil {
+				return &res, err
 			}
+			continue
 		}
+		sort.Strings(matches)
+		filenames = append(filenames, matches...)
+	}
 
-	default: // Read line by line
-		if peek[0] == '{' {
-			err = DoLines(bin, func(s string) error {
-				var e Elements
-				if err := json.Unmarshal([]byte(s), &e); err != nil {
-					return err
-				}
-				return f(e)
-			})
-		} else if MaybeKVN(peek) {
-			err = DoKVNs(bin, func(s string) error {
-				e, _, err := ParseKVN(s)
-				if err != nil {
-					return err
-				}
-				return f(*e)
-			})
-
-		} else if MaybeCSV(peek) {
-			first := true
-			err = DoLines(bin, func(s string) error {
-				if first && strings.Contains(s, ",EPOCH,") {
-					return nil
-				}
-				first = false
-				e, _, err := ParseCSV(s)
-				if err != nil {
-					return err
-				}
-				return f(*e)
-			})
-		} else {
-			err = DoTLEs(bin, 3, func(lines []string) error {
-				e, err := ParseTLE(lines[0], lines[1], lines[2])
-				if err != nil {
-					return err
-				}
-				return f(*e)
-			})
+	for _, filename := range filenames {
+		opts.Source = filename
+		if err := doFile(ctx, filename, opts, f, &res); err != nil {
+			return &res, err
 		}
 	}
 
-	return err
+	return &res, nil
+}
+
+func doFile(ctx context.Context, filename string, opts DecodeOptions, f func(Elements) error, res *DoResult) error {
+	if filename == "-" {
+		return doContext(ctx, os.Stdin, opts, f, res)
+	}
+	in, err := os.Open(filename)
+	if err != nil {
+		return res.problem(opts, err)
+	}
+	defer in.Close() // Ignore error.
+	return doContext(ctx, in, opts, f, res)
 }
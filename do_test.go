@@ -2,8 +2,15 @@ package gpelements
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -30,3 +37,250 @@ func TestDo(t *testing.T) {
 	}
 
 }
+
+func countDo(t *testing.T, in io.Reader, opts DecodeOptions) int {
+	n := 0
+	if err := DoWith(in, opts, func(e Elements) error {
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDoCompressed(t *testing.T) {
+	bs, err := ioutil.ReadFile("data/test.tle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := countDo(t, bytes.NewReader(bs), DecodeOptions{})
+
+	var buf bytes.Buffer
+	z := gzip.NewWriter(&buf)
+	if _, err = z.Write(bs); err != nil {
+		t.Fatal(err)
+	}
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countDo(t, &buf, DecodeOptions{}); n != want {
+		t.Fatalf("gzip: %d != %d", n, want)
+	}
+
+	in, err := os.Open("data/test.tle.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close() // Ignore error.
+	if n := countDo(t, in, DecodeOptions{}); n != want {
+		t.Fatalf("bzip2: %d != %d", n, want)
+	}
+}
+
+func TestDoFormat(t *testing.T) {
+	lines := strings.SplitN(testTLE, "\n", 3)
+	line1 := lines[1][0:68] + checksum(lines[1][0:68])
+
+	// Commas in the name.
+	tle := "ISS, ZARYA, A, B, C, D\n" + line1 + "\n" + lines[2] + "\n"
+	if n := countDo(t, strings.NewReader(tle), DecodeOptions{}); n != 1 {
+		t.Fatal(n)
+	}
+	if n := countDo(t, strings.NewReader(tle), DecodeOptions{Format: "tle"}); n != 1 {
+		t.Fatal(n)
+	}
+
+	e, err := ParseTLE(lines[0], line1, lines[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvn, err := e.MarshalKVN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvn = "COMMENT An element set\n" + kvn
+	if !MaybeKVN([]byte(kvn)) {
+		t.Fatal("didn't sniff KVN")
+	}
+	if n := countDo(t, strings.NewReader(kvn), DecodeOptions{Format: "kvn"}); n != 1 {
+		t.Fatal(n)
+	}
+
+	if err = DoWith(strings.NewReader(kvn), DecodeOptions{Format: "nope"}, func(e Elements) error {
+		return nil
+	}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDoFiles(t *testing.T) {
+	var (
+		sources []string
+		counts  = make(map[string]int)
+	)
+	err := DoFiles([]string{"data/test.x*", "data/test.tle*"}, DecodeOptions{}, func(e Elements) error {
+		if n := len(sources); n == 0 || sources[n-1] != e.Source {
+			sources = append(sources, e.Source)
+		}
+		counts[e.Source]++
+		if e.Record != counts[e.Source] {
+			t.Fatalf("%s record %d != %d", e.Source, e.Record, counts[e.Source])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"data/test.xml", "data/test.tle", "data/test.tle.bz2"}
+	if strings.Join(sources, ",") != strings.Join(want, ",") {
+		t.Fatal(sources)
+	}
+
+	if err = DoFiles([]string{"data/nope.*"}, DecodeOptions{}, func(e Elements) error {
+		return nil
+	}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Inputs that can't be read are errors but not failed element
+	// sets.
+	opts := DecodeOptions{
+		OnError: SkipAndCollect,
+	}
+	res, err := DoFilesContext(context.Background(), []string{"data/nope.*", "data/test.tle", "data"}, opts, func(e Elements) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Decoded == 0 || res.Failed != 0 || len(res.Errors) != 2 {
+		t.Fatalf("%#v", res)
+	}
+}
+
+func TestDoContextPolicies(t *testing.T) {
+	lines := strings.SplitN(testTLE, "\n", 3)
+	line1 := lines[1][0:68] + checksum(lines[1][0:68])
+	bad := lines[2][0:52] + "15.489x2759" + lines[2][63:68]
+	bad += checksum(bad)
+
+	var (
+		good = lines[0] + "\n" + line1 + "\n" + lines[2] + "\n"
+		in   = good + lines[0] + "\n" + line1 + "\n" + bad + "\n" + good
+		f    = func(e Elements) error {
+			return nil
+		}
+	)
+
+	if _, err := DoContext(context.Background(), strings.NewReader(in), DecodeOptions{}, f); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	opts := DecodeOptions{
+		OnError: SkipAndCollect,
+	}
+	res, err := DoContext(context.Background(), strings.NewReader(in), opts, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Decoded != 2 || res.Failed != 1 || len(res.Errors) != 1 {
+		t.Fatalf("%#v", res)
+	}
+	var pe *ParseError
+	if !errors.As(res.Errors[0], &pe) || pe.Record != 2 {
+		t.Fatalf("%#v", res.Errors[0])
+	}
+
+	// Callback errors are skipped, too, and only MaxErrors are
+	// kept.
+	opts.MaxErrors = 1
+	n := 0
+	res, err = DoContext(context.Background(), strings.NewReader(in), opts, func(e Elements) error {
+		n++
+		if n == 1 {
+			return errors.New("nope")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Decoded != 1 || res.Failed != 2 || len(res.Errors) != 1 {
+		t.Fatalf("%#v", res)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = DoContext(ctx, strings.NewReader(in), opts, f); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+func TestDoContextUnrecoverable(t *testing.T) {
+	opts := DecodeOptions{
+		OnError: SkipAndCollect,
+	}
+	for _, in := range []string{
+		`[{"OBJECT_NAME":"A","MEAN_MOTION":"x"},{"OBJECT_NAME":"B",` +
+			`"EPOCH":"2020-12-15T05:59:44.4912","MEAN_MOTION":15.5,"ECCENTRICITY":0.0001,"INCLINATION":51.6,` +
+			`"RA_OF_ASC_NODE":172.6,"ARG_OF_PERICENTER":123.4,"MEAN_ANOMALY":50.7},{"OBJECT_NAME":`,
+		`<ndm><omm><body><segment><data><meanElements><MEAN_MOTION>x</MEAN_MOTION></meanElements></data></segment></body></omm>` +
+			`<omm><body><segment><metadata><OBJECT_NAME>B</OBJECT_NAME></metadata></segment></body></omm><omm><body><`,
+	} {
+		res, err := DoContext(context.Background(), strings.NewReader(in), opts, func(e Elements) error {
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Decoded != 1 || res.Failed != 2 {
+			t.Fatalf("%s: %#v", in, res)
+		}
+	}
+}
+
+// failingReader returns its input and then an error.
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *failingReader) Read(bs []byte) (int, error) {
+	n, err := r.r.Read(bs)
+	if err == io.EOF {
+		err = r.err
+	}
+	return n, err
+}
+
+func TestDoReadError(t *testing.T) {
+	fire := errors.New("disk on fire")
+	opts := DecodeOptions{
+		Format:  "tle",
+		Source:  "test.tle",
+		OnError: SkipAndCollect,
+	}
+	in := &failingReader{
+		r:   strings.NewReader(testTLE),
+		err: fire,
+	}
+	res, err := DoContext(context.Background(), in, opts, func(e Elements) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Failed != 1 || len(res.Errors) != 1 {
+		t.Fatalf("%#v", res)
+	}
+	err = res.Errors[0]
+	var pe *ParseError
+	if !errors.Is(err, fire) || errors.As(err, &pe) {
+		t.Fatalf("%#v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "test.tle: ") {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,230 @@
+package gpelements
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Illumination is whether an object is in the Earth's shadow.
+type Illumination string
+
+const (
+	Sunlit   Illumination = "sunlit"
+	Penumbra Illumination = "penumbra"
+	Umbra    Illumination = "umbra"
+)
+
+// ShadowModel is a model of the Earth's shadow.
+type ShadowModel string
+
+const (
+	// Cylindrical treats the Sun as a point at infinity, so the
+	// shadow is a cylinder with the Earth's radius.  There's no
+	// penumbra.
+	Cylindrical ShadowModel = "cylindrical"
+
+	// Conical accounts for the Sun's size and distance, so the
+	// umbra is a cone surrounded by the penumbra.
+	Conical ShadowModel = "conical"
+)
+
+// DefaultShadowModel is used by Ephemeris.Sunlight and Eclipses when
+// no model is given.
+var DefaultShadowModel = Conical
+
+// ParseShadowModel parses "cylindrical" or "conical".
+func ParseShadowModel(s string) (ShadowModel, error) {
+	switch x := ShadowModel(strings.ToLower(s)); x {
+	case Cylindrical, Conical:
+		return x, nil
+	}
+	return "", fmt.Errorf("unknown shadow model '%s'", s)
+}
+
+// Shadow returns the illumination of an object at r given the Sun at
+// sun (both geocentric in the same frame in km) and the fraction of
+// the Sun's disk that's visible.  The Earth is a sphere with
+// DefaultEllipsoid's equatorial radius.
+func (m ShadowModel) Shadow(r, sun Vect) (Illumination, float64) {
+	var (
+		R = DefaultEllipsoid.A
+		p = r.vec3()
+		s = sun.vec3()
+	)
+
+	if m == Cylindrical {
+		var (
+			u     = s.scale(1 / s.norm())
+			along = p.dot(u)
+		)
+		if 0 <= along || R <= p.sub(u.scale(along)).norm() {
+			return Sunlit, 1
+		}
+		return Umbra, 0
+	}
+
+	// Montenbruck and Gill, "Satellite Orbits", 3.4.2.
+	var (
+		toSun = s.sub(p)
+		a     = math.Asin(SunRadius / toSun.norm())
+		b     = math.Asin(R / p.norm())
+		c     = math.Acos(clamp1(-p.dot(toSun) / (p.norm() * toSun.norm())))
+	)
+	switch {
+	case a+b <= c:
+		return Sunlit, 1
+	case c < b-a:
+		return Umbra, 0
+	case c < a-b:
+		// Annular, which can't happen near the Earth.
+		return Penumbra, 1 - b*b/(a*a)
+	}
+	var (
+		x    = (c*c + a*a - b*b) / (2 * c)
+		y    = math.Sqrt(a*a - x*x)
+		area = a*a*math.Acos(clamp1(x/a)) + b*b*math.Acos(clamp1((c-x)/b)) - c*y
+	)
+	return Penumbra, 1 - area/(math.Pi*a*a)
+}
+
+func clamp1(x float64) float64 {
+	return math.Max(-1, math.Min(1, x))
+}
+
+func (v vec3) scale(k float64) vec3 {
+	return vec3{k * v[0], k * v[1], k * v[2]}
+}
+
+// Sunlight returns the illumination of the object and the fraction of
+// the Sun's disk that's visible.  The ephemeris must be in TEME or
+// GCRF.  An empty model is DefaultShadowModel.
+func (eph Ephemeris) Sunlight(m ShadowModel) (Illumination, float64, error) {
+	if m == "" {
+		m = DefaultShadowModel
+	}
+	sun, err := sunIn(eph.Frame, eph.At)
+	if err != nil {
+		return "", 0, err
+	}
+	i, f := m.Shadow(eph.ECI, sun.Vect())
+	return i, f, nil
+}
+
+// BetaAngle returns the angle (degrees) between the orbit plane and
+// the direction to the Sun.  It's positive when the Sun is on the
+// side of the plane that the orbit goes counterclockwise around.  The
+// ephemeris must be in TEME or GCRF.
+func (eph Ephemeris) BetaAngle() (float64, error) {
+	sun, err := sunIn(eph.Frame, eph.At)
+	if err != nil {
+		return 0, err
+	}
+	h := eph.ECI.vec3().cross(eph.V.vec3())
+	return math.Asin(clamp1(h.dot(sun)/(h.norm()*sun.norm()))) * 180 / math.Pi, nil
+}
+
+// EclipseEvent is a change in illumination.
+type EclipseEvent struct {
+	// At is the first time with the new illumination.
+	At time.Time
+
+	From, To Illumination
+}
+
+// EclipseOptions control Eclipses.
+type EclipseOptions struct {
+	// Model is the shadow model.  The default is
+	// DefaultShadowModel.
+	Model ShadowModel
+
+	// Step is the interval that brackets changes before they're
+	// refined.  An eclipse shorter than Step might be missed.  The
+	// default is a hundredth of the orbital period but at most a
+	// minute.
+	Step time.Duration
+
+	// Precision is how exact the times are.  The default is 10 ms.
+	Precision time.Duration
+}
+
+// Eclipses finds the changes in the object's illumination from one
+// time to another.  The times are refined by bisection.
+func (e *Elements) Eclipses(from, to time.Time, opts EclipseOptions) ([]EclipseEvent, error) {
+	if e.Epoch == nil {
+		return nil, fmt.Errorf("no epoch")
+	}
+	prop, err := e.SGP4()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Model == "" {
+		opts.Model = DefaultShadowModel
+	}
+	opts.Step, opts.Precision = e.searchDefaults(opts.Step, opts.Precision, 100)
+
+	state := func(t time.Time) (Illumination, error) {
+		eph, err := Prop(prop, t)
+		if err != nil {
+			return "", err
+		}
+		i, _, err := eph.Sunlight(opts.Model)
+		return i, err
+	}
+
+	if !from.Before(to) {
+		return nil, nil
+	}
+
+	var events []EclipseEvent
+
+	a := from
+	sa, err := state(a)
+	if err != nil {
+		return nil, err
+	}
+	for a.Before(to) {
+		b := a.Add(opts.Step)
+		if to.Before(b) {
+			b = to
+		}
+		sb, err := state(b)
+		if err != nil {
+			return nil, err
+		}
+
+		// There could be more than one change (like from sunlit to
+		// penumbra to umbra) in a step.
+		for sa != sb {
+			lo, hi := a, b
+			for opts.Precision < hi.Sub(lo) {
+				m := lo.Add(hi.Sub(lo) / 2)
+				sm, err := state(m)
+				if err != nil {
+					return nil, err
+				}
+				if sm == sa {
+					lo = m
+				} else {
+					hi = m
+				}
+			}
+			sh, err := state(hi)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, EclipseEvent{
+				At:   hi,
+				From: sa,
+				To:   sh,
+			})
+			a, sa = hi, sh
+		}
+
+		a, sa = b, sb
+	}
+
+	return events, nil
+}
@@ -0,0 +1,153 @@
+package gpelements
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestShadow(t *testing.T) {
+	var (
+		R   = DefaultEllipsoid.A
+		sun = Vect{X: AU}
+	)
+	for _, c := range []struct {
+		model ShadowModel
+		r     Vect
+		want  Illumination
+	}{
+		{Cylindrical, Vect{X: 7000}, Sunlit},
+		{Cylindrical, Vect{X: -7000}, Umbra},
+		{Cylindrical, Vect{X: -7000, Y: R - 1}, Umbra},
+		{Cylindrical, Vect{X: -7000, Y: R + 1}, Sunlit},
+		{Cylindrical, Vect{X: 1, Z: 7000}, Sunlit},
+		{Conical, Vect{X: 7000}, Sunlit},
+		{Conical, Vect{X: -7000}, Umbra},
+		{Conical, Vect{X: -7000, Y: R}, Penumbra},
+		{Conical, Vect{X: -7000, Y: R - 100}, Umbra},
+		{Conical, Vect{X: -7000, Y: R + 100}, Sunlit},
+		{Conical, Vect{X: -2e6}, Penumbra},
+	} {
+		got, f := c.model.Shadow(c.r, sun)
+		if got != c.want {
+			t.Fatalf("%s %v: %s != %s", c.model, c.r, got, c.want)
+		}
+		switch {
+		case got == Sunlit && f != 1,
+			got == Umbra && f != 0,
+			got == Penumbra && (f <= 0 || 1 <= f):
+			t.Fatalf("%s %v: %s with %f", c.model, c.r, got, f)
+		}
+	}
+
+	// The fraction increases across the penumbra.
+	last := 0.0
+	for y := R - 40; y < R+40; y++ {
+		_, f := Conical.Shadow(Vect{X: -7000, Y: y}, sun)
+		if f < last {
+			t.Fatalf("%f at %f < %f", f, y, last)
+		}
+		last = f
+	}
+	if last != 1 {
+		t.Fatal(last)
+	}
+}
+
+func TestBetaAngle(t *testing.T) {
+	at := time.Date(2020, 12, 21, 10, 2, 0, 0, time.UTC)
+	sun := SunPosition(at).vec3()
+
+	// An equatorial orbit's beta angle is the Sun's declination.
+	eph := Ephemeris{
+		At:    at,
+		Frame: GCRF,
+		ECI:   Vect{X: 7000},
+		V:     Vect{Y: 7.5},
+	}
+	beta, err := eph.BetaAngle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec := math.Asin(sun[2]/sun.norm()) * 180 / math.Pi; 1e-9 < math.Abs(beta-dec) {
+		t.Fatalf("%f != %f", beta, dec)
+	}
+
+	// An orbit whose plane contains the Sun has a beta angle of 0.
+	eph.ECI = sun.scale(7000 / sun.norm()).Vect()
+	eph.V = Vect{Z: 7.5}
+	if beta, err = eph.BetaAngle(); err != nil {
+		t.Fatal(err)
+	}
+	if 1e-9 < math.Abs(beta) {
+		t.Fatal(beta)
+	}
+
+	eph.Frame = ITRF
+	if _, err = eph.BetaAngle(); err == nil {
+		t.Fatal("should have complained")
+	}
+}
+
+func TestEclipses(t *testing.T) {
+	e := testElements(t)
+	o, err := e.SGP4()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		from = time.Time(*e.Epoch)
+		to   = from.Add(6 * time.Hour)
+	)
+
+	state := func(at time.Time, m ShadowModel) Illumination {
+		eph, err := Prop(o, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		i, _, err := eph.Sunlight(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return i
+	}
+
+	for _, m := range []ShadowModel{Cylindrical, Conical} {
+		events, err := e.Eclipses(from, to, EclipseOptions{Model: m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) < 4 {
+			t.Fatalf("%s: only %d events", m, len(events))
+		}
+
+		last := state(from, m)
+		for _, x := range events {
+			if x.From != last || x.From == x.To {
+				t.Fatalf("%s: %#v after %s", m, x, last)
+			}
+			if state(x.At, m) != x.To || state(x.At.Add(-20*time.Millisecond), m) != x.From {
+				t.Fatalf("%s: %#v", m, x)
+			}
+			last = x.To
+		}
+
+		// Every change in sampled illumination has an event.
+		step := 10 * time.Second
+		for at := from; at.Before(to); at = at.Add(step) {
+			if state(at, m) == state(at.Add(step), m) {
+				continue
+			}
+			found := false
+			for _, x := range events {
+				if at.Before(x.At) && !at.Add(step).Before(x.At) {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("%s: no event after %s", m, at)
+			}
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package gpelements
 
 import (
+	"encoding/json"
 	"encoding/xml"
+	"fmt"
 )
 
 type Elements struct {
@@ -14,12 +16,32 @@ type Elements struct {
 
 	Originator string `json:",omitempty" xml:"header>ORIGINATOR"`
 
+	// MessageId: "MESSAGE_ID": "OMM 201113719185"
+	MessageId string `json:"MESSAGE_ID,omitempty" xml:"header>MESSAGE_ID,omitempty"`
+
 	// Name: "OBJECT_NAME": "STARLINK-1329"
 	Name string `json:"OBJECT_NAME,omitempty" xml:"body>segment>metadata>OBJECT_NAME"`
 
 	// Id: "OBJECT_ID": "2020-025A",
 	Id string `json:"OBJECT_ID,omitempty" xml:"body>segment>metadata>OBJECT_ID"`
 
+	// CenterName: "CENTER_NAME": "EARTH"
+	CenterName string `json:"CENTER_NAME,omitempty" xml:"body>segment>metadata>CENTER_NAME"`
+
+	// RefFrame: "REF_FRAME": "TEME"
+	RefFrame string `json:"REF_FRAME,omitempty" xml:"body>segment>metadata>REF_FRAME"`
+
+	// RefFrameEpoch: "REF_FRAME_EPOCH": "2000-01-01T12:00:00"
+	//
+	// Only needed for frames without an implied epoch.
+	RefFrameEpoch *Time `json:"REF_FRAME_EPOCH,omitempty" xml:"body>segment>metadata>REF_FRAME_EPOCH,omitempty"`
+
+	// TimeSystem: "TIME_SYSTEM": "UTC"
+	TimeSystem string `json:"TIME_SYSTEM,omitempty" xml:"body>segment>metadata>TIME_SYSTEM"`
+
+	// MeanElementTheory: "MEAN_ELEMENT_THEORY": "SGP4"
+	MeanElementTheory string `json:"MEAN_ELEMENT_THEORY,omitempty" xml:"body>segment>metadata>MEAN_ELEMENT_THEORY"`
+
 	LaunchYear  int    `json:"-" xml:"-"`
 	LaunchNum   int    `json:"-" xml:"-"`
 	LaunchPiece string `json:"-" xml:"-"`
@@ -45,6 +67,12 @@ type Elements struct {
 	// MeanAnomaly: "MEAN_ANOMALY": 278.0774,
 	MeanAnomaly float64 `json:"MEAN_ANOMALY" xml:"body>segment>data>meanElements>MEAN_ANOMALY"`
 
+	// GM: "GM": 398600.8 (km**3/s**2)
+	GM float64 `json:"GM,omitempty" xml:"body>segment>data>meanElements>GM,omitempty"`
+
+	// SpacecraftParameters are optional.
+	*SpacecraftParameters
+
 	// EphemerisType: "EPHEMERIS_TYPE": 0,
 	EphemerisType int `json:"EPHEMERIS_TYPE" xml:"body>segment>data>tleParameters>EPHEMERIS_TYPE"`
 
@@ -73,9 +101,233 @@ type Elements struct {
 
 	// MeanMontionDDOT: "MEAN_MOTION_DDOT": 0
 	MeanMotionDDot float64 `json:"MEAN_MOTION_DDOT" xml:"body>segment>data>tleParameters>MEAN_MOTION_DDOT"`
+
+	// Covariance is optional.
+	*Covariance
+
+	// UserDefined: "USER_DEFINED_EARTH_MODEL": "WGS-84"
+	//
+	// In JSON, each parameter is a USER_DEFINED_* key.
+	UserDefined []UserDefined `json:"-" xml:"body>segment>data>userDefinedParameters>USER_DEFINED,omitempty"`
+
+	// Extras are the source's fields that aren't part of the OMM
+	// (like space-track.com's "RCS_SIZE") in their original order.
+	//
+	// Output formats that can will re-emit them.
+	Extras []Extra `json:"-" xml:"-"`
+
+	// Warnings are about problems found (but tolerated) when
+	// parsing.  See ParseOptions.
+	Warnings []string `json:"-" xml:"-"`
+
+	// Source is the name of the input (like a filename) that the
+	// element set came from, if known.
+	Source string `json:"-" xml:"-"`
+
+	// Record is the ordinal (starting at 1) of the element set in
+	// its input.
+	Record int `json:"-" xml:"-"`
+}
+
+// Extra is a field that's not part of the OMM.
+type Extra struct {
+	Key string
+
+	// Value is a string unless it came from JSON, in which case
+	// it can be anything that encoding/json produces with
+	// UseNumber (including nil).
+	Value interface{}
+}
+
+// Text returns the Value as a string.
+func (x Extra) Text() string {
+	switch v := x.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return string(v)
+	default:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(bs)
+	}
+}
+
+// GetExtra returns the value of the given extra field.
+func (e *Elements) GetExtra(k string) (interface{}, bool) {
+	for _, x := range e.Extras {
+		if x.Key == k {
+			return x.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetExtra sets (or adds) the given extra field.
+func (e *Elements) SetExtra(k string, v interface{}) {
+	for i, x := range e.Extras {
+		if x.Key == k {
+			e.Extras[i].Value = v
+			return
+		}
+	}
+	e.Extras = append(e.Extras, Extra{
+		Key:   k,
+		Value: v,
+	})
+}
+
+// StripExtras removes all extra fields.
+func (e *Elements) StripExtras() {
+	e.Extras = nil
+}
+
+// SpacecraftParameters are the OMM's optional spacecraft parameters.
+// Zero values are omitted.
+type SpacecraftParameters struct {
+	// Mass: "MASS": 1913.0 (kg)
+	Mass float64 `json:"MASS,omitempty" xml:"body>segment>data>spacecraftParameters>MASS,omitempty"`
+
+	// SolarRadArea: "SOLAR_RAD_AREA": 10.0 (m**2)
+	SolarRadArea float64 `json:"SOLAR_RAD_AREA,omitempty" xml:"body>segment>data>spacecraftParameters>SOLAR_RAD_AREA,omitempty"`
+
+	// SolarRadCoeff: "SOLAR_RAD_COEFF": 1.3
+	SolarRadCoeff float64 `json:"SOLAR_RAD_COEFF,omitempty" xml:"body>segment>data>spacecraftParameters>SOLAR_RAD_COEFF,omitempty"`
+
+	// DragArea: "DRAG_AREA": 10.0 (m**2)
+	DragArea float64 `json:"DRAG_AREA,omitempty" xml:"body>segment>data>spacecraftParameters>DRAG_AREA,omitempty"`
+
+	// DragCoeff: "DRAG_COEFF": 2.3
+	DragCoeff float64 `json:"DRAG_COEFF,omitempty" xml:"body>segment>data>spacecraftParameters>DRAG_COEFF,omitempty"`
+}
+
+// Covariance is the lower triangle of the OMM's optional 6x6
+// position/velocity covariance matrix (km**2, km**2/s, km**2/s**2).
+type Covariance struct {
+	// CovRefFrame: "COV_REF_FRAME": "TEME"
+	//
+	// Empty means the REF_FRAME of the metadata.
+	CovRefFrame string `json:"COV_REF_FRAME,omitempty" xml:"body>segment>data>covarianceMatrix>COV_REF_FRAME,omitempty"`
+
+	CXX float64 `json:"CX_X" xml:"body>segment>data>covarianceMatrix>CX_X"`
+
+	CYX float64 `json:"CY_X" xml:"body>segment>data>covarianceMatrix>CY_X"`
+	CYY float64 `json:"CY_Y" xml:"body>segment>data>covarianceMatrix>CY_Y"`
+
+	CZX float64 `json:"CZ_X" xml:"body>segment>data>covarianceMatrix>CZ_X"`
+	CZY float64 `json:"CZ_Y" xml:"body>segment>data>covarianceMatrix>CZ_Y"`
+	CZZ float64 `json:"CZ_Z" xml:"body>segment>data>covarianceMatrix>CZ_Z"`
+
+	CXDotX    float64 `json:"CX_DOT_X" xml:"body>segment>data>covarianceMatrix>CX_DOT_X"`
+	CXDotY    float64 `json:"CX_DOT_Y" xml:"body>segment>data>covarianceMatrix>CX_DOT_Y"`
+	CXDotZ    float64 `json:"CX_DOT_Z" xml:"body>segment>data>covarianceMatrix>CX_DOT_Z"`
+	CXDotXDot float64 `json:"CX_DOT_X_DOT" xml:"body>segment>data>covarianceMatrix>CX_DOT_X_DOT"`
+
+	CYDotX    float64 `json:"CY_DOT_X" xml:"body>segment>data>covarianceMatrix>CY_DOT_X"`
+	CYDotY    float64 `json:"CY_DOT_Y" xml:"body>segment>data>covarianceMatrix>CY_DOT_Y"`
+	CYDotZ    float64 `json:"CY_DOT_Z" xml:"body>segment>data>covarianceMatrix>CY_DOT_Z"`
+	CYDotXDot float64 `json:"CY_DOT_X_DOT" xml:"body>segment>data>covarianceMatrix>CY_DOT_X_DOT"`
+	CYDotYDot float64 `json:"CY_DOT_Y_DOT" xml:"body>segment>data>covarianceMatrix>CY_DOT_Y_DOT"`
+
+	CZDotX    float64 `json:"CZ_DOT_X" xml:"body>segment>data>covarianceMatrix>CZ_DOT_X"`
+	CZDotY    float64 `json:"CZ_DOT_Y" xml:"body>segment>data>covarianceMatrix>CZ_DOT_Y"`
+	CZDotZ    float64 `json:"CZ_DOT_Z" xml:"body>segment>data>covarianceMatrix>CZ_DOT_Z"`
+	CZDotXDot float64 `json:"CZ_DOT_X_DOT" xml:"body>segment>data>covarianceMatrix>CZ_DOT_X_DOT"`
+	CZDotYDot float64 `json:"CZ_DOT_Y_DOT" xml:"body>segment>data>covarianceMatrix>CZ_DOT_Y_DOT"`
+	CZDotZDot float64 `json:"CZ_DOT_Z_DOT" xml:"body>segment>data>covarianceMatrix>CZ_DOT_Z_DOT"`
+}
+
+// lower returns pointers to the lower triangle in the order given by
+// CovarianceKeywords.
+func (c *Covariance) lower() []*float64 {
+	return []*float64{
+		&c.CXX,
+		&c.CYX, &c.CYY,
+		&c.CZX, &c.CZY, &c.CZZ,
+		&c.CXDotX, &c.CXDotY, &c.CXDotZ, &c.CXDotXDot,
+		&c.CYDotX, &c.CYDotY, &c.CYDotZ, &c.CYDotXDot, &c.CYDotYDot,
+		&c.CZDotX, &c.CZDotY, &c.CZDotZ, &c.CZDotXDot, &c.CZDotYDot, &c.CZDotZDot,
+	}
+}
+
+// Matrix returns the full, symmetric matrix with rows and columns
+// ordered X, Y, Z, X_DOT, Y_DOT, Z_DOT.
+func (c *Covariance) Matrix() [6][6]float64 {
+	var (
+		m  [6][6]float64
+		xs = c.lower()
+		k  = 0
+	)
+	for i := 0; i < 6; i++ {
+		for j := 0; j <= i; j++ {
+			m[i][j] = *xs[k]
+			m[j][i] = *xs[k]
+			k++
+		}
+	}
+	return m
+}
+
+// SetMatrix sets the lower triangle from the given matrix.
+func (c *Covariance) SetMatrix(m [6][6]float64) {
+	var (
+		xs = c.lower()
+		k  = 0
+	)
+	for i := 0; i < 6; i++ {
+		for j := 0; j <= i; j++ {
+			*xs[k] = m[i][j]
+			k++
+		}
+	}
+}
+
+// CovarianceKeywords returns the keywords for the lower triangle of
+// the covariance matrix in their standard order: CX_X, CY_X, CY_Y,
+// CZ_X, ..., CZ_DOT_Z_DOT.
+func CovarianceKeywords() []string {
+	var (
+		axes = []string{"X", "Y", "Z", "X_DOT", "Y_DOT", "Z_DOT"}
+		acc  = make([]string, 0, 21)
+	)
+	for i, row := range axes {
+		for _, col := range axes[0 : i+1] {
+			acc = append(acc, "C"+row+"_"+col)
+		}
+	}
+	return acc
+}
+
+// UserDefined is a USER_DEFINED parameter.
+type UserDefined struct {
+	// Parameter is the name without the "USER_DEFINED_" prefix.
+	Parameter string `xml:"parameter,attr"`
+	Value     string `xml:",chardata"`
 }
 
+// Copy returns a deep copy.
 func (e *Elements) Copy() *Elements {
 	acc := *e
+	if e.SpacecraftParameters != nil {
+		sp := *e.SpacecraftParameters
+		acc.SpacecraftParameters = &sp
+	}
+	if e.Covariance != nil {
+		c := *e.Covariance
+		acc.Covariance = &c
+	}
+	if e.UserDefined != nil {
+		acc.UserDefined = append([]UserDefined(nil), e.UserDefined...)
+	}
+	if e.Extras != nil {
+		acc.Extras = append([]Extra(nil), e.Extras...)
+	}
+	if e.Warnings != nil {
+		acc.Warnings = append([]string(nil), e.Warnings...)
+	}
 	return &acc
 }
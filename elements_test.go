@@ -1,6 +1,8 @@
 package gpelements
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"log"
 	"strconv"
 	"testing"
@@ -28,3 +30,85 @@ func TestEncode(t *testing.T) {
 
 	log.Printf("DEBUG %s %s %s %s", s, id, e, d)
 }
+
+func TestFullOMM(t *testing.T) {
+	e := testElements(t)
+
+	e.MessageId = "OMM 201113719185"
+	e.RefFrameEpoch = e.Epoch
+	e.GM = 398600.8
+	e.SpacecraftParameters = &SpacecraftParameters{
+		Mass:      419725,
+		DragCoeff: 2.2,
+	}
+	e.Covariance = &Covariance{
+		CovRefFrame: "TEME",
+	}
+	var m [6][6]float64
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 6; j++ {
+			m[i][j] = float64(1+i+j) / 1000
+		}
+	}
+	e.Covariance.SetMatrix(m)
+	e.SetUserDefined("EARTH_MODEL", "WGS-84")
+	e.SetUserDefined("FOO", "bar")
+
+	want, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		check := NewElements()
+		if err := json.Unmarshal(want, check); err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(check)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Fatalf("\n%s\n%s", got, want)
+		}
+		if check.Covariance.Matrix() != m {
+			t.Fatal(check.Covariance.Matrix())
+		}
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		bs, err := xml.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check := NewElements()
+		if err := xml.Unmarshal(bs, check); err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(check)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Fatalf("\n%s\n%s\n%s", bs, got, want)
+		}
+	})
+
+	t.Run("KVN", func(t *testing.T) {
+		kvn, err := e.MarshalKVN()
+		if err != nil {
+			t.Fatal(err)
+		}
+		check, _, err := ParseKVN(kvn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(check)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Fatalf("\n%s\n%s\n%s", kvn, got, want)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package gpelements
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError describes a problem parsing an element set.
+//
+// Fields that aren't known are zero.  Use errors.As to get a
+// ParseError from an error returned by a parser or a Do function.
+type ParseError struct {
+	// Source is the name of the input (like a filename).
+	Source string
+
+	// Format is the input format ("tle", "csv", "kvn", "json", or
+	// "xml").
+	Format string
+
+	// Record is the ordinal (starting at 1) of the element set in
+	// the input.
+	Record int
+
+	// Line is the number (starting at 1) of the line in the input.
+	// For an error from ParseTLE, it's the line (1 or 2) of the
+	// TLE.
+	Line int
+
+	// Field is the OMM keyword of the offending value.
+	Field string
+
+	// Columns is the range (starting at 1 and inclusive) of TLE
+	// columns of the offending value.
+	Columns [2]int
+
+	// Text is the offending text.
+	Text string
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	var parts []string
+	if e.Source != "" {
+		parts = append(parts, e.Source)
+	}
+	if e.Format != "" {
+		parts = append(parts, e.Format)
+	}
+	if e.Record != 0 {
+		parts = append(parts, fmt.Sprintf("record %d", e.Record))
+	}
+	if e.Line != 0 {
+		parts = append(parts, fmt.Sprintf("line %d", e.Line))
+	}
+	switch c := e.Columns; {
+	case c[0] == 0:
+	case c[0] == c[1]:
+		parts = append(parts, fmt.Sprintf("column %d", c[0]))
+	default:
+		parts = append(parts, fmt.Sprintf("columns %d-%d", c[0], c[1]))
+	}
+	if e.Field != "" {
+		parts = append(parts, e.Field)
+	}
+	if e.Text != "" {
+		parts = append(parts, "'"+e.Text+"'")
+	}
+	if len(parts) == 0 {
+		return e.Err.Error()
+	}
+	return strings.Join(parts, " ") + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// recordError returns err as a ParseError with the given format,
+// record, and line unless it already says what they are.
+func recordError(err error, format string, record, line int) *ParseError {
+	pe, is := err.(*ParseError)
+	if is {
+		c := *pe
+		pe = &c
+	} else {
+		pe = &ParseError{
+			Err: err,
+		}
+	}
+	if pe.Format == "" {
+		pe.Format = format
+	}
+	if pe.Record == 0 {
+		pe.Record = record
+	}
+	if pe.Line == 0 {
+		pe.Line = line
+	}
+	return pe
+}
@@ -0,0 +1,111 @@
+package gpelements
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorTLE(t *testing.T) {
+	lines := strings.SplitN(testTLE, "\n", 3)
+
+	// Mean motion in columns 53-63 of line 2.
+	bad := lines[2][0:52] + "15.489x2759" + lines[2][63:68]
+	bad += checksum(bad)
+
+	in := "\n" + lines[0] + "\n" + lines[1] + "\n\n" + lines[2] + "\n" +
+		lines[0] + "\n" + lines[1] + "\n" + bad + "\n"
+
+	err := DoTLE(bufio.NewReader(strings.NewReader(in)), func(e Elements) error {
+		return nil
+	})
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("%#v", err)
+	}
+	if pe.Format != "tle" || pe.Record != 2 || pe.Line != 8 || pe.Field != "MEAN_MOTION" ||
+		pe.Columns != [2]int{53, 63} || pe.Text != "15.489x2759" {
+		t.Fatalf("%#v", pe)
+	}
+}
+
+func TestParseErrorKVN(t *testing.T) {
+	e := testElements(t)
+	kvn, err := e.MarshalKVN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := kvn + strings.Replace(kvn, "INCLINATION    = ", "INCLINATION    = x", 1)
+
+	err = DoKVN(bufio.NewReader(strings.NewReader(in)), func(e Elements) error {
+		return nil
+	})
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("%#v", err)
+	}
+	if pe.Format != "kvn" || pe.Record != 2 || pe.Field != "INCLINATION" {
+		t.Fatalf("%#v", pe)
+	}
+	if want := strings.Count(kvn, "\n"); pe.Line <= want {
+		t.Fatalf("line %d <= %d", pe.Line, want)
+	}
+}
+
+func TestParseErrorCSV(t *testing.T) {
+	in := CSVHeader + "\n" +
+		`ISS (ZARYA),1998-067A,2020-09-18T16:13:57.999936,15.48952759,.0000884,51.6432,245.8351,104.2674,236.9442,0,U,25544,999,24650,.12514E-4,.241E-5,0` + "\n" +
+		`ISS (ZARYA),1998-067A,2020-09-18T16:13:57.999936,15.48952759,.0000884,51.6432,245.8351,104.2674,236.9442,0,U,25544,999,24650,.12514E-4,.241E-5,0` + "\n" +
+		"\n" +
+		`ISS (ZARYA),1998-067A,2020-09-18T16:13:57.999936,15.48952759,.0000884,fifty,245.8351,104.2674,236.9442,0,U,25544,999,24650,.12514E-4,.241E-5,0` + "\n"
+
+	err := DoCSV(bufio.NewReader(strings.NewReader(in)), func(e Elements) error {
+		return nil
+	})
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("%#v", err)
+	}
+	if pe.Format != "csv" || pe.Record != 3 || pe.Line != 5 || pe.Field != "INCLINATION" || pe.Text != "fifty" {
+		t.Fatalf("%#v", pe)
+	}
+}
+
+func TestDoLinesLineNumbers(t *testing.T) {
+	var (
+		in   = "a\n\nb\nc\n"
+		fail = errors.New("fail")
+	)
+	err := DoLines(bufio.NewReader(strings.NewReader(in)), func(s string) error {
+		if s == "c" {
+			return fail
+		}
+		return nil
+	})
+
+	// The caller's own error isn't a ParseError.
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		t.Fatalf("%#v", err)
+	}
+	if !errors.Is(err, fail) || !strings.Contains(err.Error(), "line 4") {
+		t.Fatal(err)
+	}
+
+	err = DoLines(bufio.NewReader(strings.NewReader(in)), func(s string) error {
+		if s == "c" {
+			return &ParseError{Field: "X", Err: fail}
+		}
+		return nil
+	})
+	if !errors.As(err, &pe) {
+		t.Fatalf("%#v", err)
+	}
+	if pe.Line != 4 {
+		t.Fatal(pe.Line)
+	}
+}
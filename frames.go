@@ -0,0 +1,294 @@
+package gpelements
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Frame is a coordinate frame.
+type Frame string
+
+const (
+	// TEME is the true equator, mean equinox frame of SGP4's
+	// output.
+	TEME Frame = "teme"
+
+	// GCRF is approximated by the mean equator and equinox of
+	// J2000 (using IAU 1976 precession and IAU 1980 nutation and
+	// ignoring the tiny frame bias).
+	GCRF Frame = "gcrf"
+
+	// ITRF is the Earth-fixed (ECEF) frame.  Polar motion comes
+	// from DefaultEOP.
+	ITRF Frame = "itrf"
+)
+
+// ParseFrame parses "teme", "gcrf" (or "j2000"), or "itrf" (or
+// "ecef").
+func ParseFrame(s string) (Frame, error) {
+	switch strings.ToLower(s) {
+	case "teme":
+		return TEME, nil
+	case "gcrf", "j2000":
+		return GCRF, nil
+	case "itrf", "ecef":
+		return ITRF, nil
+	}
+	return "", fmt.Errorf("unknown frame '%s'", s)
+}
+
+// earthRate is the Earth's rotation rate (rad/s).
+const earthRate = 7.292115146706979e-5
+
+const arcsec = math.Pi / 180 / 3600
+
+// mat3 is a 3x3 rotation matrix.
+type mat3 [3][3]float64
+
+// vec3 is a 3-vector.
+type vec3 [3]float64
+
+// rotX is the rotation of the coordinate frame by a (rad) about X.
+func rotX(a float64) mat3 {
+	c, s := math.Cos(a), math.Sin(a)
+	return mat3{
+		{1, 0, 0},
+		{0, c, s},
+		{0, -s, c},
+	}
+}
+
+// rotY is the rotation of the coordinate frame by a (rad) about Y.
+func rotY(a float64) mat3 {
+	c, s := math.Cos(a), math.Sin(a)
+	return mat3{
+		{c, 0, -s},
+		{0, 1, 0},
+		{s, 0, c},
+	}
+}
+
+// rotZ is the rotation of the coordinate frame by a (rad) about Z.
+func rotZ(a float64) mat3 {
+	c, s := math.Cos(a), math.Sin(a)
+	return mat3{
+		{c, s, 0},
+		{-s, c, 0},
+		{0, 0, 1},
+	}
+}
+
+func (m mat3) mul(n mat3) mat3 {
+	var p mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				p[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return p
+}
+
+func (m mat3) transpose() mat3 {
+	var t mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			t[i][j] = m[j][i]
+		}
+	}
+	return t
+}
+
+func (m mat3) apply(v vec3) vec3 {
+	var w vec3
+	for i := 0; i < 3; i++ {
+		for k := 0; k < 3; k++ {
+			w[i] += m[i][k] * v[k]
+		}
+	}
+	return w
+}
+
+func (v vec3) sub(w vec3) vec3 {
+	return vec3{v[0] - w[0], v[1] - w[1], v[2] - w[2]}
+}
+
+func (v vec3) dot(w vec3) float64 {
+	return v[0]*w[0] + v[1]*w[1] + v[2]*w[2]
+}
+
+func (v vec3) norm() float64 {
+	return math.Sqrt(v.dot(v))
+}
+
+func (v vec3) cross(w vec3) vec3 {
+	return vec3{
+		v[1]*w[2] - v[2]*w[1],
+		v[2]*w[0] - v[0]*w[2],
+		v[0]*w[1] - v[1]*w[0],
+	}
+}
+
+func (v Vect) vec3() vec3 {
+	return vec3{v.X, v.Y, v.Z}
+}
+
+func (v vec3) Vect() Vect {
+	return Vect{v[0], v[1], v[2]}
+}
+
+// ttCenturies returns Julian centuries of TT since J2000 for the UTC
+// time.
+func ttCenturies(t time.Time) float64 {
+	tt, _ := TT.FromUTC(t)
+	j2000 := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	return tt.Sub(j2000).Hours() / 24 / 36525
+}
+
+// precession returns the IAU 1976 precession matrix from J2000 to the
+// mean equator and equinox of date.
+func precession(T float64) mat3 {
+	var (
+		zeta  = (2306.2181*T + 0.30188*T*T + 0.017998*T*T*T) * arcsec
+		z     = (2306.2181*T + 1.09468*T*T + 0.018203*T*T*T) * arcsec
+		theta = (2004.3109*T - 0.42665*T*T - 0.041833*T*T*T) * arcsec
+	)
+	return rotZ(-z).mul(rotY(theta)).mul(rotZ(-zeta))
+}
+
+// nutationTerm is a term of the IAU 1980 nutation series.  The
+// multipliers are of l, l', F, D, and Omega.  The coefficients are in
+// units of 0.0001".
+type nutationTerm struct {
+	l, lp, f, d, om int
+	psi, psiT       float64
+	eps, epsT       float64
+}
+
+// nutationTerms are the largest terms of the IAU 1980 series.  The
+// rest are each at most 0.0015".
+var nutationTerms = []nutationTerm{
+	{0, 0, 0, 0, 1, -171996, -174.2, 92025, 8.9},
+	{0, 0, 2, -2, 2, -13187, -1.6, 5736, -3.1},
+	{0, 0, 2, 0, 2, -2274, -0.2, 977, -0.5},
+	{0, 0, 0, 0, 2, 2062, 0.2, -895, 0.5},
+	{0, 1, 0, 0, 0, 1426, -3.4, 54, -0.1},
+	{1, 0, 0, 0, 0, 712, 0.1, -7, 0},
+	{0, 1, 2, -2, 2, -517, 1.2, 224, -0.6},
+	{0, 0, 2, 0, 1, -386, -0.4, 200, 0},
+	{1, 0, 2, 0, 2, -301, 0, 129, -0.1},
+	{0, -1, 2, -2, 2, 217, -0.5, -95, 0.3},
+	{1, 0, 0, -2, 0, -158, 0, -1, 0},
+	{0, 0, 2, -2, 1, 129, 0.1, -70, 0},
+	{-1, 0, 2, 0, 2, 123, 0, -53, 0},
+	{1, 0, 0, 0, 1, 63, 0.1, -33, 0},
+	{0, 0, 0, 2, 0, 63, 0, -2, 0},
+	{-1, 0, 2, 2, 2, -59, 0, 26, 0},
+	{-1, 0, 0, 0, 1, -58, -0.1, 32, 0},
+	{1, 0, 2, 0, 1, -51, 0, 27, 0},
+	{2, 0, 0, -2, 0, 48, 0, 1, 0},
+	{-2, 0, 2, 0, 1, 46, 0, -24, 0},
+	{0, 0, 2, 2, 2, -38, 0, 16, 0},
+	{2, 0, 2, 0, 2, -31, 0, 13, 0},
+	{2, 0, 0, 0, 0, 29, 0, -1, 0},
+	{1, 0, 2, -2, 2, 29, 0, -12, 0},
+	{0, 0, 2, 0, 0, 26, 0, -1, 0},
+	{0, 0, 2, -2, 0, -22, 0, 0, 0},
+	{-1, 0, 2, 0, 1, 21, 0, -10, 0},
+	{0, 2, 0, 0, 0, 17, -0.1, 0, 0},
+	{0, 2, 2, -2, 2, -16, 0.1, 7, 0},
+	{-1, 0, 0, 2, 1, 16, 0, -8, 0},
+}
+
+// nutation returns the nutation in longitude and obliquity and the
+// mean obliquity (all in radians).
+func nutation(T float64) (dpsi, deps, eps0 float64) {
+	deg := func(x float64) float64 {
+		return math.Mod(x, 360) * math.Pi / 180
+	}
+	var (
+		D  = deg(297.85036 + 445267.111480*T - 0.0019142*T*T + T*T*T/189474)
+		lp = deg(357.52772 + 35999.050340*T - 0.0001603*T*T - T*T*T/300000)
+		l  = deg(134.96298 + 477198.867398*T + 0.0086972*T*T + T*T*T/56250)
+		F  = deg(93.27191 + 483202.017538*T - 0.0036825*T*T + T*T*T/327270)
+		om = deg(125.04452 - 1934.136261*T + 0.0020708*T*T + T*T*T/450000)
+	)
+	for _, x := range nutationTerms {
+		a := float64(x.l)*l + float64(x.lp)*lp + float64(x.f)*F + float64(x.d)*D + float64(x.om)*om
+		dpsi += (x.psi + x.psiT*T) * math.Sin(a)
+		deps += (x.eps + x.epsT*T) * math.Cos(a)
+	}
+	dpsi *= 0.0001 * arcsec
+	deps *= 0.0001 * arcsec
+	eps0 = (84381.448 - 46.8150*T - 0.00059*T*T + 0.001813*T*T*T) * arcsec
+	return
+}
+
+// temeToGCRF returns the matrix from TEME to GCRF at the UTC time.
+func temeToGCRF(t time.Time) mat3 {
+	var (
+		T                = ttCenturies(t)
+		dpsi, deps, eps0 = nutation(T)
+		eqe              = dpsi * math.Cos(eps0)
+
+		// From mean of date to true of date.
+		n = rotX(-(eps0 + deps)).mul(rotZ(-dpsi)).mul(rotX(eps0))
+
+		// From TEME to true of date.
+		e = rotZ(-eqe)
+	)
+	return precession(T).transpose().mul(n.transpose()).mul(e)
+}
+
+// polarMotion returns the matrix from the pseudo Earth-fixed frame to
+// ITRF using DefaultEOP.
+func polarMotion(t time.Time) mat3 {
+	var (
+		r      = DefaultEOP.At(t)
+		xp, yp = r.PolarX * arcsec, r.PolarY * arcsec
+	)
+	return rotY(-xp).mul(rotX(-yp))
+}
+
+// TEMEToGCRF converts a TEME position and velocity at the UTC time to
+// GCRF.
+func TEMEToGCRF(t time.Time, r, v Vect) (Vect, Vect) {
+	m := temeToGCRF(t)
+	return m.apply(r.vec3()).Vect(), m.apply(v.vec3()).Vect()
+}
+
+// TEMEToITRF converts a TEME position and velocity at the UTC time to
+// ITRF.  The velocity is relative to the rotating Earth.
+func TEMEToITRF(t time.Time, r, v Vect) (Vect, Vect) {
+	var (
+		gmst, _ = TimeToGST(t)
+		m       = rotZ(gmst)
+		pm      = polarMotion(t)
+		rpef    = m.apply(r.vec3())
+		vpef    = m.apply(v.vec3()).sub(vec3{0, 0, earthRate}.cross(rpef))
+	)
+	return pm.apply(rpef).Vect(), pm.apply(vpef).Vect()
+}
+
+// InFrame returns the ephemeris, which must be in TEME, in the frame.
+func (eph Ephemeris) InFrame(f Frame) (Ephemeris, error) {
+	if eph.Frame == f {
+		return eph, nil
+	}
+	if eph.Frame != TEME {
+		return eph, fmt.Errorf("can't convert from %s to %s", eph.Frame, f)
+	}
+	switch f {
+	case GCRF:
+		eph.ECI, eph.V = TEMEToGCRF(eph.At, eph.ECI, eph.V)
+	case ITRF:
+		eph.ECI, eph.V = TEMEToITRF(eph.At, eph.ECI, eph.V)
+	default:
+		return eph, fmt.Errorf("unknown frame '%s'", f)
+	}
+	eph.Frame = f
+	return eph, nil
+}
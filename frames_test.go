@@ -0,0 +1,83 @@
+package gpelements
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Vallado's example (Fundamentals of Astrodynamics and Applications,
+// 4th ed., example 3-15 and the TEME paper's test case).
+var (
+	valladoAt = time.Date(2004, 4, 6, 7, 51, 28, 386009000, time.UTC)
+	valladoR  = Vect{5094.18016210, 6127.64465950, 6380.34453270}
+	valladoV  = Vect{-4.746131487, 0.785818041, 5.531931288}
+	valladoE  = &EOP{
+		Records: []EOPRecord{
+			{
+				MJD:         53101,
+				UT1MinusUTC: -0.4399619,
+				PolarX:      -0.140682,
+				PolarY:      0.333309,
+			},
+		},
+	}
+)
+
+func near(t *testing.T, what string, got, want Vect, tolerance float64) {
+	d := math.Sqrt(math.Pow(got.X-want.X, 2) +
+		math.Pow(got.Y-want.Y, 2) +
+		math.Pow(got.Z-want.Z, 2))
+	if tolerance < d {
+		t.Fatalf("%s: %v differs from %v by %f", what, got, want, d)
+	}
+}
+
+func TestTEMEToITRF(t *testing.T) {
+	defer func(was *EOP) {
+		DefaultEOP = was
+	}(DefaultEOP)
+	DefaultEOP = valladoE
+
+	r, v := TEMEToITRF(valladoAt, valladoR, valladoV)
+	near(t, "r", r, Vect{-1033.4793830, 7901.2952754, 6380.3565958}, 0.00001)
+	near(t, "v", v, Vect{-3.225636520, -2.872451450, 5.531924446}, 0.0000001)
+}
+
+func TestTEMEToGCRF(t *testing.T) {
+	r, v := TEMEToGCRF(valladoAt, valladoR, valladoV)
+
+	// Within a few meters because of the truncated nutation
+	// series and the frame bias.
+	near(t, "r", r, Vect{5102.508958, 6123.011401, 6378.136928}, 0.002)
+	near(t, "v", v, Vect{-4.74322016, 0.79053650, 5.53375528}, 0.00001)
+}
+
+func TestInFrame(t *testing.T) {
+	eph := Ephemeris{
+		At:    valladoAt,
+		Frame: TEME,
+		Units: EphemerisUnits,
+		ECI:   valladoR,
+		V:     valladoV,
+	}
+	for _, s := range []string{"teme", "gcrf", "itrf", "J2000", "ecef"} {
+		f, err := ParseFrame(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, err := eph.InFrame(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x.Frame != f || !x.At.Equal(eph.At) {
+			t.Fatalf("%#v", x)
+		}
+		if _, err = x.InFrame(TEME); f != TEME && err == nil {
+			t.Fatalf("expected an error converting from %s", f)
+		}
+	}
+	if _, err := ParseFrame("icrf"); err == nil {
+		t.Fatal("expected an error for icrf")
+	}
+}
@@ -0,0 +1,76 @@
+package gpelements
+
+import (
+	"math"
+)
+
+// Ellipsoid is a reference ellipsoid for geodetic coordinates.
+type Ellipsoid struct {
+	Name string
+
+	// A is the equatorial radius in km.
+	A float64
+
+	// F is the flattening.
+	F float64
+}
+
+var (
+	WGS84 = Ellipsoid{Name: "WGS-84", A: 6378.137, F: 1 / 298.257223563}
+	WGS72 = Ellipsoid{Name: "WGS-72", A: 6378.135, F: 1 / 298.26}
+)
+
+// DefaultEllipsoid is used by ECIToLLA.
+var DefaultEllipsoid = WGS84
+
+// ToGeodetic converts an Earth-fixed position (km) to geodetic
+// latitude and longitude (degrees) and altitude (km).
+//
+// This conversion is Vermeille's closed form ("Direct transformation
+// from geocentric coordinates to geodetic coordinates", 2002), which
+// is good everywhere except within about 40 km of the Earth's center.
+func (el Ellipsoid) ToGeodetic(p Vect) LatLonAlt {
+	var (
+		a2 = el.A * el.A
+		e2 = el.F * (2 - el.F)
+		e4 = e2 * e2
+
+		xy2 = p.X*p.X + p.Y*p.Y
+		xy  = math.Sqrt(xy2)
+
+		P = xy2 / a2
+		q = (1 - e2) / a2 * p.Z * p.Z
+		r = (P + q - e4) / 6
+		s = e4 * P * q / (4 * r * r * r)
+		t = math.Cbrt(1 + s + math.Sqrt(s*(2+s)))
+		u = r * (1 + t + 1/t)
+		v = math.Sqrt(u*u + e4*q)
+		w = e2 * (u + v - q) / (2 * v)
+		k = math.Sqrt(u+v+w*w) - w
+		D = k * xy / (k + e2)
+		h = math.Hypot(D, p.Z)
+	)
+
+	return LatLonAlt{
+		Lat: 2 * math.Atan2(p.Z, D+h) * 180 / math.Pi,
+		Lon: math.Atan2(p.Y, p.X) * 180 / math.Pi,
+		Alt: (k + e2 - 1) / k * h,
+	}
+}
+
+// FromGeodetic converts geodetic latitude and longitude (degrees) and
+// altitude (km) to an Earth-fixed position (km).
+func (el Ellipsoid) FromGeodetic(lla LatLonAlt) Vect {
+	var (
+		e2       = el.F * (2 - el.F)
+		lat, lon = lla.Lat * math.Pi / 180, lla.Lon * math.Pi / 180
+		sinLat   = math.Sin(lat)
+		cosLat   = math.Cos(lat)
+		n        = el.A / math.Sqrt(1-e2*sinLat*sinLat)
+	)
+	return Vect{
+		X: (n + lla.Alt) * cosLat * math.Cos(lon),
+		Y: (n + lla.Alt) * cosLat * math.Sin(lon),
+		Z: (n*(1-e2) + lla.Alt) * sinLat,
+	}
+}
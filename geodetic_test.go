@@ -0,0 +1,121 @@
+package gpelements
+
+import (
+	"bufio"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	sat "github.com/jsmorph/go-satellite"
+)
+
+func TestGeodeticRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for _, el := range []Ellipsoid{WGS84, WGS72} {
+		for i := 0; i < 10000; i++ {
+			lla := LatLonAlt{
+				Lat: rng.Float64()*180 - 90,
+				Lon: rng.Float64()*360 - 180,
+				Alt: rng.Float64()*40000 - 10,
+			}
+			got := el.ToGeodetic(el.FromGeodetic(lla))
+			if 1e-9 < math.Abs(got.Lat-lla.Lat) || 1e-9 < math.Abs(got.Lon-lla.Lon) ||
+				1e-6 < math.Abs(got.Alt-lla.Alt) {
+				t.Fatalf("%s: %#v != %#v", el.Name, got, lla)
+			}
+		}
+	}
+
+	// Poles and the equator.
+	for _, lla := range []LatLonAlt{{90, 0, 400}, {-90, 0, 0}, {0, 180, 35786}} {
+		got := WGS84.ToGeodetic(WGS84.FromGeodetic(lla))
+		if 1e-9 < math.Abs(got.Lat-lla.Lat) || 1e-6 < math.Abs(got.Alt-lla.Alt) {
+			t.Fatalf("%#v != %#v", got, lla)
+		}
+	}
+}
+
+// satLLA is what ECIToLLA used to do.
+func satLLA(t time.Time, p Vect) LatLonAlt {
+	gmst, _ := TimeToGST(t)
+	alt, _, ll := sat.ECIToLLA(sat.Vector3{X: p.X, Y: p.Y, Z: p.Z}, gmst)
+	lon := math.Remainder(ll.Longitude, 2*math.Pi)
+	return LatLonAlt{
+		Lat: ll.Latitude * 180 / math.Pi,
+		Lon: lon * 180 / math.Pi,
+		Alt: alt,
+	}
+}
+
+func TestECIToLLA(t *testing.T) {
+	in, err := os.Open("data/test.tle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close() // Ignore error.
+
+	at := time.Date(2020, 12, 16, 0, 0, 0, 0, time.UTC)
+
+	f := func(lines []string) error {
+		e, err := ParseTLE(lines[0], lines[1], lines[2])
+		if err != nil {
+			return err
+		}
+		o, err := e.SGP4()
+		if err != nil {
+			return err
+		}
+		s, err := Prop(o, at)
+		if err != nil {
+			return nil // Some have decayed.
+		}
+		got, err := ECIToLLA(at, s.ECI)
+		if err != nil {
+			return err
+		}
+		want := satLLA(at, s.ECI)
+		dLon := math.Abs(math.Remainder(got.Lon-want.Lon, 360))
+		if 1e-9 < math.Abs(got.Lat-want.Lat) || 1e-9 < dLon || 1e-6 < math.Abs(got.Alt-want.Alt) {
+			t.Fatalf("%s: %#v != %#v", e.Name, got, want)
+		}
+		return nil
+	}
+
+	if err := DoTLEs(bufio.NewReader(in), 3, f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var benchPoint = Vect{-1033.4793830, 7901.2952754, 6380.3565958}
+
+func BenchmarkToGeodetic(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		WGS84.ToGeodetic(benchPoint)
+	}
+}
+
+func BenchmarkFromGeodetic(b *testing.B) {
+	lla := WGS84.ToGeodetic(benchPoint)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		WGS84.FromGeodetic(lla)
+	}
+}
+
+func BenchmarkECIToLLA(b *testing.B) {
+	at := time.Date(2020, 12, 16, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < b.N; i++ {
+		if _, err := ECIToLLA(at, benchPoint); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSatECIToLLA(b *testing.B) {
+	x := sat.Vector3{X: benchPoint.X, Y: benchPoint.Y, Z: benchPoint.Z}
+	for i := 0; i < b.N; i++ {
+		sat.ECIToLLA(x, 0)
+	}
+}
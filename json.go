@@ -1,7 +1,13 @@
 package gpelements
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"regexp"
+	"strings"
 )
 
 // This file exists because space-track.com JSON element sets use
@@ -28,6 +34,7 @@ var destringThese = map[string]bool{
 
 var strPair = regexp.MustCompile(`"([A-Z_]+)":"([-0-9Ee+.]+)"`)
 
+// Deprecated: Elements.UnmarshalJSON accepts quoted numbers directly.
 func DestringNumbers(src string) string {
 	return strPair.ReplaceAllStringFunc(src, func(s string) string {
 		kv := strPair.FindStringSubmatch(s)
@@ -37,3 +44,373 @@ func DestringNumbers(src string) string {
 		return s
 	})
 }
+
+// jsonAliases maps the keys that MarshalJSON writes for some fields
+// to their OMM keywords.
+var jsonAliases = map[string]string{
+	"CreationDate": "CREATION_DATE",
+	"Originator":   "ORIGINATOR",
+}
+
+// MarshalJSON adds a USER_DEFINED_* key for each UserDefined
+// parameter and then the Extras to the usual struct-based
+// representation.
+func (e Elements) MarshalJSON() ([]byte, error) {
+	type plain Elements // Without methods.
+
+	bs, err := json.Marshal(plain(e))
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make([]jsonField, 0, len(e.UserDefined)+len(e.Extras))
+	for _, u := range e.UserDefined {
+		v, err := json.Marshal(u.Value)
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, jsonField{
+			Key:   UserDefinedPrefix + u.Parameter,
+			Value: v,
+		})
+	}
+	for _, x := range e.Extras {
+		v, err := json.Marshal(x.Value)
+		if err != nil {
+			return nil, wrapErrf(err, "%s", x.Key)
+		}
+		kvs = append(kvs, jsonField{
+			Key:   x.Key,
+			Value: v,
+		})
+	}
+
+	return appendJSONFields(bs, kvs)
+}
+
+// UnmarshalJSON sets fields by OMM keyword.
+//
+// Each value can be a JSON number, a string (space-track.com quotes
+// numbers), or null, which (like an empty string) leaves the field
+// alone.  Unknown keys go to Extras.
+func (e *Elements) UnmarshalJSON(bs []byte) error {
+	_, err := e.unmarshalJSON(bs)
+	return err
+}
+
+// unmarshalJSON is UnmarshalJSON that also returns the OMM keywords
+// that had values.
+func (e *Elements) unmarshalJSON(bs []byte) (map[string]bool, error) {
+	kvs, err := jsonFields(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	have := make(map[string]bool, len(kvs))
+	for _, kv := range kvs {
+		k := kv.Key
+		if alias, have := jsonAliases[k]; have {
+			k = alias
+		}
+		field, known := lookupField(k)
+		if !known {
+			v, err := jsonValue(kv.Value)
+			if err != nil {
+				return nil, jsonError(k, kv.Value, err)
+			}
+			e.SetExtra(k, v)
+			continue
+		}
+		s, err := jsonText(kv.Value)
+		if err != nil {
+			return nil, jsonError(k, kv.Value, err)
+		}
+		if s == "" {
+			continue
+		}
+		ok, err := field.setValue(e, s)
+		if err != nil {
+			return nil, jsonError(k, kv.Value, err)
+		}
+		have[k] = ok
+	}
+
+	if err = e.UseUTC(); err != nil {
+		return nil, &ParseError{
+			Format: "json",
+			Field:  "TIME_SYSTEM",
+			Text:   e.TimeSystem,
+			Err:    err,
+		}
+	}
+
+	return have, nil
+}
+
+// jsonElements makes Elements from a JSON object, which (unlike
+// with UnmarshalJSON) must have a value for each keyword in
+// ommRequired.
+func jsonElements(bs []byte) (*Elements, error) {
+	e := NewElements()
+	have, err := e.unmarshalJSON(bs)
+	if err != nil {
+		return nil, err
+	}
+	if k, missing := missingRequired(have); missing {
+		return nil, &ParseError{
+			Format: "json",
+			Field:  k,
+			Err:    fmt.Errorf("missing value"),
+		}
+	}
+	return e, nil
+}
+
+func jsonError(k string, v json.RawMessage, err error) *ParseError {
+	return &ParseError{
+		Format: "json",
+		Field:  k,
+		Text:   string(v),
+		Err:    err,
+	}
+}
+
+// jsonField is a key and its (raw) value in a JSON object.
+type jsonField struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// jsonFields returns the fields of a JSON object in order.
+func jsonFields(bs []byte) ([]jsonField, error) {
+	d := json.NewDecoder(bytes.NewReader(bs))
+
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, is := tok.(json.Delim); !is || delim != '{' {
+		return nil, fmt.Errorf("expected a JSON object, not %v", tok)
+	}
+
+	acc := make([]jsonField, 0, 32)
+	for d.More() {
+		if tok, err = d.Token(); err != nil {
+			return nil, err
+		}
+		k, is := tok.(string)
+		if !is {
+			return nil, fmt.Errorf("expected a key, not %v", tok)
+		}
+		var v json.RawMessage
+		if err = d.Decode(&v); err != nil {
+			return nil, wrapErrf(err, "%s", k)
+		}
+		acc = append(acc, jsonField{
+			Key:   k,
+			Value: v,
+		})
+	}
+
+	return acc, nil
+}
+
+// appendJSONFields adds fields to the end of a marshaled JSON
+// object.
+func appendJSONFields(bs []byte, kvs []jsonField) ([]byte, error) {
+	if len(kvs) == 0 {
+		return bs, nil
+	}
+
+	bs = bytes.TrimRight(bs, " \n")
+	if len(bs) < 2 || bs[len(bs)-1] != '}' {
+		return nil, fmt.Errorf("not a JSON object: %s", bs)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(bs[0 : len(bs)-1])
+	sep := ","
+	if len(bs) == 2 { // {}
+		sep = ""
+	}
+	for _, kv := range kvs {
+		k, err := json.Marshal(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(sep)
+		buf.Write(k)
+		buf.WriteString(":")
+		buf.Write(kv.Value)
+		sep = ","
+	}
+	buf.WriteString("}")
+
+	return buf.Bytes(), nil
+}
+
+// jsonValue decodes any JSON value with UseNumber.
+func jsonValue(v json.RawMessage) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(v))
+	d.UseNumber()
+	var x interface{}
+	err := d.Decode(&x)
+	return x, err
+}
+
+// jsonText returns the text of a JSON scalar: a string's value, a
+// number's literal, or "" for null.
+func jsonText(v json.RawMessage) (string, error) {
+	v = bytes.TrimSpace(v)
+	if len(v) == 0 {
+		return "", nil
+	}
+	switch v[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(v, &s)
+		return s, err
+	case '{', '[':
+		return "", fmt.Errorf("not a scalar: %s", v)
+	}
+	if string(v) == "null" {
+		return "", nil
+	}
+	return string(v), nil
+}
+
+// DoJSONArray calls f on each element set in a JSON array as it's
+// read from the input.
+func DoJSONArray(r io.Reader, f func(Elements) error) error {
+	return doElements(newJSONArrayReader(r), f)
+}
+
+// jsonArrayReader reads the element sets in a JSON array.
+type jsonArrayReader struct {
+	d       *json.Decoder
+	started bool
+	done    bool
+	record  int
+}
+
+func newJSONArrayReader(r io.Reader) *jsonArrayReader {
+	return &jsonArrayReader{
+		d: json.NewDecoder(r),
+	}
+}
+
+func (r *jsonArrayReader) Read() (*Elements, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	if !r.started {
+		r.done = true // Unless we can start.
+		tok, err := r.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if delim, is := tok.(json.Delim); !is || delim != '[' {
+			return nil, fmt.Errorf("expected a JSON array, not %v", tok)
+		}
+		r.done = false
+		r.started = true
+	}
+
+	if !r.d.More() {
+		r.done = true
+		if _, err := r.d.Token(); err != nil { // ']'
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	r.record++
+	var bs json.RawMessage
+	if err := r.d.Decode(&bs); err != nil {
+		// The decoder isn't at the next element.
+		r.done = true
+		return nil, recordError(err, "json", r.record, 0)
+	}
+	e, err := jsonElements(bs)
+	if err != nil {
+		return nil, recordError(err, "json", r.record, 0)
+	}
+	return e, nil
+}
+
+// jsonLinesReader reads element sets that are each a JSON object on
+// a line by itself.
+type jsonLinesReader struct {
+	r      *bufio.Reader
+	line   int
+	record int
+}
+
+func (r *jsonLinesReader) Read() (*Elements, error) {
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" && err == io.EOF {
+			return nil, io.EOF
+		}
+		r.line++
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		r.record++
+		e, err := jsonElements([]byte(line))
+		if err != nil {
+			return nil, recordError(err, "json", r.record, r.line)
+		}
+		return e, nil
+	}
+}
+
+// jsonWriter writes each element set on a line by itself or (with
+// array) as an indented JSON array.
+type jsonWriter struct {
+	w     io.Writer
+	array bool
+	n     int
+}
+
+func (w *jsonWriter) Write(e *Elements) error {
+	if !w.array {
+		bs, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.w, "%s\n", bs)
+		return err
+	}
+
+	bs, err := json.MarshalIndent(e, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if w.n == 0 {
+		sep = "[\n  "
+	}
+	w.n++
+	_, err = fmt.Fprintf(w.w, "%s%s", sep, bs)
+	return err
+}
+
+func (w *jsonWriter) Close() error {
+	if !w.array {
+		return nil
+	}
+	end := "\n]\n"
+	if w.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(w.w, end)
+	return err
+}
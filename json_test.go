@@ -1,6 +1,9 @@
 package gpelements
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -13,3 +16,114 @@ func TestDestring(t *testing.T) {
 }
 
 var destringTestInput = `[{"CCSDS_OMM_VERS":"2.0","CREATION_DATE":"2020-12-14T03:56:29","ORIGINATOR":"TEST","OBJECT_NAME":"FREGAT R\/B","OBJECT_ID":"2011-037ND","CENTER_NAME":"EARTH","REF_FRAME":"TEME","TIME_SYSTEM":"UTC","MEAN_ELEMENT_THEORY":"SGP4","EPOCH":"2020-12-20T09:45:08.123456","MEAN_MOTION":"0.11033380","ECCENTRICITY":"0.75935440","INCLINATION":"57.8123","RA_OF_ASC_NODE":"98.7987","ARG_OF_PERICENTER":"234.7777","MEAN_ANOMALY":"356.2514","EPHEMERIS_TYPE":"0","CLASSIFICATION_TYPE":"U","NORAD_CAT_ID":"46736","ELEMENT_SET_NO":"999","REV_AT_EPOCH":"11","BSTAR":"0.00000000000000","MEAN_MOTION_DOT":"-0.00001600","MEAN_MOTION_DDOT":"0.0000000000000","SEMIMAJOR_AXIS":"183315.104","PERIOD":"13018.384","APOAPSIS":"316138.100","PERIAPSIS":"37735.838","OBJECT_TYPE":"ROCKET BODY","RCS_SIZE":null,"COUNTRY_CODE":"CIS","LAUNCH_DATE":"2011-07-18"}]`
+
+func TestDoJSONArray(t *testing.T) {
+	var es []Elements
+	err := DoJSONArray(strings.NewReader(destringTestInput), func(e Elements) error {
+		es = append(es, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 {
+		t.Fatal(len(es))
+	}
+	e := es[0]
+	if e.MeanMotion != 0.11033380 {
+		t.Fatal(e.MeanMotion)
+	}
+	if e.ElementSet != 999 {
+		t.Fatal(e.ElementSet)
+	}
+	if e.NoradCatId != "46736" {
+		t.Fatal(e.NoradCatId)
+	}
+	if e.CreationDate == nil {
+		t.Fatal("no CREATION_DATE")
+	}
+}
+
+func TestJSONMissingRequired(t *testing.T) {
+	obj := destringTestInput[1 : len(destringTestInput)-1]
+	for _, v := range []string{`null`, `""`} {
+		bad := strings.Replace(obj, `"2020-12-20T09:45:08.123456"`, v, 1)
+		for _, c := range []struct {
+			format, in string
+		}{
+			{"json", bad + "\n"},
+			{"jsonarray", "[" + bad + "," + obj + "]"},
+		} {
+			codec, _ := LookupCodec(c.format)
+			r := codec.NewReader(bufio.NewReader(strings.NewReader(c.in)), DefaultParseOptions)
+			_, err := r.Read()
+			var pe *ParseError
+			if !errors.As(err, &pe) || pe.Field != "EPOCH" {
+				t.Fatalf("%s %s: %v", c.format, v, err)
+			}
+			if c.format == "jsonarray" {
+				if _, err = r.Read(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	// UnmarshalJSON itself doesn't care.
+	e := NewElements()
+	if err := json.Unmarshal([]byte(`{"EPOCH":null}`), e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	e := testElements(t)
+	js, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := NewElements()
+	if err = json.Unmarshal(js, check); err != nil {
+		t.Fatal(err)
+	}
+	jsc, err := json.Marshal(check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(js) != string(jsc) {
+		t.Fatal(string(jsc))
+	}
+}
+
+func TestJSONExtras(t *testing.T) {
+	e := NewElements()
+	if err := json.Unmarshal([]byte(destringTestInput[1:len(destringTestInput)-1]), e); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, have := e.GetExtra("RCS_SIZE"); !have || v != nil {
+		t.Fatal(v)
+	}
+	if v, have := e.GetExtra("PERIOD"); !have || v != "13018.384" {
+		t.Fatal(v)
+	}
+	if last := e.Extras[len(e.Extras)-1]; last.Key != "LAUNCH_DATE" {
+		t.Fatal(last.Key)
+	}
+
+	js, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(js), `"OBJECT_TYPE":"ROCKET BODY","RCS_SIZE":null,"COUNTRY_CODE":"CIS","LAUNCH_DATE":"2011-07-18"}`) {
+		t.Fatal(string(js))
+	}
+
+	e.StripExtras()
+	if js, err = json.Marshal(e); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(js), "RCS_SIZE") {
+		t.Fatal(string(js))
+	}
+}
@@ -7,60 +7,111 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 var KVNTimeFormat = "2006-01-02T15:04:05.999999999"
 
 func (e *Elements) MarshalKVN() (string, error) {
-	f := `CCSDS_OMM_VERS = 2.0
-CREATION_DATE  = %s
-ORIGINATOR     = %s
-
-OBJECT_NAME    = %s
-OBJECT_ID      = %s
-CENTER_NAME    = EARTH
-REF_FRAME      = TEME
-TIME_SYSTEM    = UTC
-MEAN_ELEMENT_THEORY = SGP/SGP4
-
-EPOCH          = %s
-MEAN_MOTION    = %g
-ECCENTRICITY   = %g
-INCLINATION    = %g
-RA_OF_ASC_NODE = %g
-ARG_OF_PERICENTER = %g
-MEAN_ANOMALY   = %g
-
-EPHEMERIS_TYPE = %d
-CLASSIFICATION_TYPE = %s
-NORAD_CAT_ID   = %s
-ELEMENT_SET_NO = %d
-REV_AT_EPOCH   = %d
-BSTAR          = %e
-MEAN_MOTION_DOT = %e
-MEAN_MOTION_DDOT = %e
-`
-	return fmt.Sprintf(f,
-		e.CreationDate,
-		e.Originator,
-		e.Name,
-		e.Id,
-		e.Epoch.Format(KVNTimeFormat),
-		e.MeanMotion,
-		e.Eccentricity,
-		e.Inclination,
-		e.RightAscension,
-		e.ArgOfPericenter,
-		e.MeanAnomaly,
-		e.EphemerisType,
-		e.ClassificationType,
-		e.NoradCatId,
-		e.ElementSet,
-		int(e.RevAtEpoch),
-		e.BStar,
-		e.MeanMotionDot,
-		e.MeanMotionDDot,
-	), nil
+	var (
+		b  strings.Builder
+		kv = func(k, format string, v interface{}) {
+			fmt.Fprintf(&b, "%-14s = "+format+"\n", k, v)
+		}
+	)
+
+	kv("CCSDS_OMM_VERS", "%s", "2.0")
+	if e.CreationDate != nil {
+		kv("CREATION_DATE", "%s", e.CreationDate.Format(KVNTimeFormat))
+	}
+	kv("ORIGINATOR", "%s", e.Originator)
+	if e.MessageId != "" {
+		kv("MESSAGE_ID", "%s", e.MessageId)
+	}
+	b.WriteString("\n")
+
+	kv("OBJECT_NAME", "%s", e.Name)
+	kv("OBJECT_ID", "%s", e.Id)
+	kv("CENTER_NAME", "%s", or(e.CenterName, "EARTH"))
+	kv("REF_FRAME", "%s", or(e.RefFrame, "TEME"))
+	if e.RefFrameEpoch != nil {
+		kv("REF_FRAME_EPOCH", "%s", e.RefFrameEpoch.Format(KVNTimeFormat))
+	}
+	kv("TIME_SYSTEM", "%s", or(e.TimeSystem, "UTC"))
+	kv("MEAN_ELEMENT_THEORY", "%s", or(e.MeanElementTheory, "SGP/SGP4"))
+	b.WriteString("\n")
+
+	kv("EPOCH", "%s", e.Epoch.Format(KVNTimeFormat))
+	kv("MEAN_MOTION", "%g", e.MeanMotion)
+	kv("ECCENTRICITY", "%g", e.Eccentricity)
+	kv("INCLINATION", "%g", e.Inclination)
+	kv("RA_OF_ASC_NODE", "%g", e.RightAscension)
+	kv("ARG_OF_PERICENTER", "%g", e.ArgOfPericenter)
+	kv("MEAN_ANOMALY", "%g", e.MeanAnomaly)
+	if e.GM != 0 {
+		kv("GM", "%g", e.GM)
+	}
+	b.WriteString("\n")
+
+	if sp := e.SpacecraftParameters; sp != nil {
+		for _, p := range []struct {
+			k string
+			x float64
+		}{
+			{"MASS", sp.Mass},
+			{"SOLAR_RAD_AREA", sp.SolarRadArea},
+			{"SOLAR_RAD_COEFF", sp.SolarRadCoeff},
+			{"DRAG_AREA", sp.DragArea},
+			{"DRAG_COEFF", sp.DragCoeff},
+		} {
+			if p.x != 0 {
+				kv(p.k, "%g", p.x)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	kv("EPHEMERIS_TYPE", "%d", e.EphemerisType)
+	kv("CLASSIFICATION_TYPE", "%s", e.ClassificationType)
+	kv("NORAD_CAT_ID", "%s", e.NoradCatId)
+	kv("ELEMENT_SET_NO", "%d", e.ElementSet)
+	kv("REV_AT_EPOCH", "%d", int(e.RevAtEpoch))
+	kv("BSTAR", "%e", e.BStar)
+	kv("MEAN_MOTION_DOT", "%e", e.MeanMotionDot)
+	kv("MEAN_MOTION_DDOT", "%e", e.MeanMotionDDot)
+
+	if c := e.Covariance; c != nil {
+		b.WriteString("\n")
+		if c.CovRefFrame != "" {
+			kv("COV_REF_FRAME", "%s", c.CovRefFrame)
+		}
+		xs := c.lower()
+		for i, k := range CovarianceKeywords() {
+			kv(k, "%g", *xs[i])
+		}
+	}
+
+	if 0 < len(e.UserDefined)+len(e.Extras) {
+		b.WriteString("\n")
+		for _, u := range e.UserDefined {
+			kv(UserDefinedPrefix+u.Parameter, "%s", u.Value)
+		}
+		// KVN only allows standard keywords, so Extras become
+		// USER_DEFINED parameters.
+		for _, x := range e.Extras {
+			kv(UserDefinedPrefix+x.Key, "%s", x.Text())
+		}
+	}
+
+	return b.String(), nil
+}
+
+// or returns def if s is empty.
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
 }
 
 type String string
@@ -85,8 +136,6 @@ func (s *String) Scan(state fmt.ScanState, verb rune) error {
 
 }
 
-var newlines = regexp.MustCompile("\n+")
-
 // ParseInternationalDesignator tries to parse strings like "1998-067A".
 func ParseInternationalDesignator(s string) (y int, n int, p string, err error) {
 	y = 0
@@ -134,147 +183,279 @@ func (e *Elements) UseInternationalDesignator() error {
 	return nil
 }
 
+// kvnUnkept are standard OMM keywords that ParseKVN accepts but
+// doesn't keep.  The value says whether the keyword's value must be a
+// number.
+var kvnUnkept = map[string]bool{
+	// SEMI_MAJOR_AXIS is an alternative to MEAN_MOTION for
+	// theories other than SGP4, and we need MEAN_MOTION.
+	"SEMI_MAJOR_AXIS": true,
+}
+
+// kvnUnits matches a trailing unit specification like "[km]", which
+// only a numeric value can have.
+var kvnUnits = regexp.MustCompile(`\s*\[[^\]]*\]$`)
+
+// isKVNComment reports whether the (trimmed) line is a COMMENT.
+func isKVNComment(line string) bool {
+	const c = "COMMENT"
+	return strings.HasPrefix(line, c) && (len(line) == len(c) || unicode.IsSpace(rune(line[len(c)])))
+}
+
+// ParseKVN parses one OMM in KVN format (CCSDS 502.0-B-2).
+//
+// Keywords can appear in any order.  Blank lines and COMMENT lines are
+// ignored, and numeric values can have units in brackets (like
+// "[km]").
+// Unknown keywords go to Extras.  Errors name the offending line.
+//
+// The returned int is the number of keyword lines.
 func ParseKVN(s string) (*Elements, int, error) {
+	return parseKVN(s, 1, DefaultParseOptions)
+}
 
-	s = newlines.ReplaceAllString(s, "\n")
+// parseKVN is ParseKVN with the number of the first line (for error
+// messages) and options.
+func parseKVN(s string, line int, opts ParseOptions) (*Elements, int, error) {
+	var (
+		e    = NewElements()
+		n    = 0
+		seen = make(map[string]bool, 32)
+		have = make(map[string]bool, 32)
+	)
 
-	{
-		// Scanf has a hard time with zero-length %s input.
-		//
-		// Should gensym.
+	for i, text := range strings.Split(s, "\n") {
+		var k string
+		lineErr := func(err error) error {
+			return &ParseError{
+				Format: "kvn",
+				Line:   line + i,
+				Field:  k,
+				Text:   text,
+				Err:    err,
+			}
+		}
 
-		missing := "null"
+		text = strings.TrimSpace(text)
+		if text == "" || isKVNComment(text) {
+			continue
+		}
 
-		s = strings.ReplaceAll(s, "= \n", "= "+missing+"\n")
-	}
+		at := strings.Index(text, "=")
+		if at < 0 {
+			return nil, n, lineErr(fmt.Errorf("no '='"))
+		}
+		k = strings.TrimSpace(text[0:at])
+		v := strings.TrimSpace(text[at+1:])
+		n++
 
-	f := `CCSDS_OMM_VERS = 2.0
-CREATION_DATE  = %s
-ORIGINATOR     = %s
-OBJECT_NAME    = %s
-OBJECT_ID      = %s
-CENTER_NAME    = EARTH
-REF_FRAME      = TEME
-TIME_SYSTEM    = UTC
-MEAN_ELEMENT_THEORY = SGP/SGP4
-EPOCH          = %s
-MEAN_MOTION    = %f
-ECCENTRICITY   = %f
-INCLINATION    = %f
-RA_OF_ASC_NODE = %f
-ARG_OF_PERICENTER = %f
-MEAN_ANOMALY   = %f
-EPHEMERIS_TYPE = %d
-CLASSIFICATION_TYPE = %s
-NORAD_CAT_ID   = %s
-ELEMENT_SET_NO = %d
-REV_AT_EPOCH   = %f
-BSTAR          = %e
-MEAN_MOTION_DOT = %e
-MEAN_MOTION_DDOT = %e
-`
-	f = regexp.MustCompile("\n+").ReplaceAllString(f, "\n")
+		if seen[k] {
+			return nil, n, lineErr(fmt.Errorf("duplicate keyword %s", k))
+		}
+		seen[k] = true
+
+		field, known := lookupField(k)
+		numeric, unkept := kvnUnkept[k]
+		switch {
+		case known:
+			numeric = field.numeric
+		case !unkept:
+			// An unknown keyword's value is a number if it
+			// looks like one.
+			_, err := strconv.ParseFloat(kvnUnits.ReplaceAllString(v, ""), 64)
+			numeric = err == nil
+		}
+		if numeric {
+			v = kvnUnits.ReplaceAllString(v, "")
+		}
 
-	var (
-		epoch        string
-		e            = &Elements{}
-		creationDate String
-		originator   String
-		name         String
-	)
+		if v == "" {
+			continue
+		}
 
-	n, err := fmt.Sscanf(s,
-		f,
-		&creationDate,
-		&originator,
-		&name,
-		&e.Id,
-		&epoch,
-		&e.MeanMotion,
-		&e.Eccentricity,
-		&e.Inclination,
-		&e.RightAscension,
-		&e.ArgOfPericenter,
-		&e.MeanAnomaly,
-		&e.EphemerisType,
-		&e.ClassificationType,
-		&e.NoradCatId,
-		&e.ElementSet,
-		&e.RevAtEpoch,
-		&e.BStar,
-		&e.MeanMotionDot,
-		&e.MeanMotionDDot,
-	)
+		if known {
+			ok, err := field.setValue(e, v)
+			if err != nil {
+				return nil, n, lineErr(err)
+			}
+			have[k] = ok
+			continue
+		}
 
-	if err != nil {
-		return nil, n, err
+		if !unkept {
+			e.SetExtra(k, v)
+			continue
+		}
+		if numeric {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, n, lineErr(err)
+			}
+		}
+	}
+
+	if k, missing := missingRequired(have); missing {
+		err := fmt.Errorf("missing keyword")
+		if seen[k] {
+			err = fmt.Errorf("missing value")
+		}
+		return nil, n, &ParseError{
+			Format: "kvn",
+			Line:   line,
+			Field:  k,
+			Err:    err,
+		}
 	}
 
-	e.Originator = string(originator)
 	if e.Originator == "null" {
 		e.Originator = ""
 	}
 
-	e.Name = string(name)
-
 	{
 		y, num, p, err := ParseInternationalDesignator(e.Id)
 		if err != nil {
-			return nil, n, fmt.Errorf("failed to parse international designator '%s': %s", e.Id, err)
+			err = &ParseError{
+				Format: "kvn",
+				Field:  "OBJECT_ID",
+				Text:   e.Id,
+				Err:    wrapErrf(err, "failed to parse international designator"),
+			}
+			if err = opts.problem(e, true, err); err != nil {
+				return nil, n, err
+			}
 		}
 		e.LaunchYear = y
 		e.LaunchNum = num
 		e.LaunchPiece = p
 	}
 
-	if creationDate == "null" {
-		creationDate = ""
+	if err := e.UseUTC(); err != nil {
+		return nil, n, &ParseError{
+			Format: "kvn",
+			Line:   line,
+			Field:  "TIME_SYSTEM",
+			Text:   e.TimeSystem,
+			Err:    err,
+		}
 	}
 
-	if 0 < len(creationDate) {
-		e.CreationDate, err = ParseTime(KVNTimeFormat, string(creationDate))
-		if err != nil {
-			return nil, n, err
+	return e, n, nil
+}
 
-		}
+// DoKVNs calls f with the text of each OMM in the input.  Each OMM
+// starts with a CCSDS_OMM_VERS line.
+func DoKVNs(r *bufio.Reader, f func(s string) error) error {
+	kr := &kvnReader{
+		r: r,
 	}
-
-	if e.Epoch, err = ParseTime(KVNTimeFormat, epoch); err != nil {
-		return nil, n, err
+	for {
+		s, _, err := kr.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = f(s); err != nil {
+			return err
+		}
 	}
+}
 
-	return e, n, nil
+// DoKVN calls f on each element set parsed from OMMs in KVN format.
+func DoKVN(r *bufio.Reader, f func(Elements) error) error {
+	return doElements(&kvnReader{r: r, opts: DefaultParseOptions}, f)
 }
 
-func DoKVNs(r *bufio.Reader, f func(s string) error) error {
+// kvnReader reads OMMs in KVN format.
+type kvnReader struct {
+	r    *bufio.Reader
+	opts ParseOptions
+
+	line   int
+	record int
+	eof    bool
+
+	// lines are the lines of the OMM being read, which started on
+	// line start.
+	lines []string
+	start int
 
+	// started is true after the first CCSDS_OMM_VERS line.
+	started bool
+}
+
+// next returns the text of the next OMM and the number of its first
+// line or io.EOF.
+func (r *kvnReader) next() (string, int, error) {
 	var (
 		first = "CCSDS_OMM_VERS"
-		lines []string
-		emit  = func() error {
-			if lines != nil {
-				return f(strings.Join(lines, "\n"))
-			}
-			return nil
+		flush = func() (string, int) {
+			s, start := strings.Join(r.lines, "\n"), r.start
+			r.lines = nil
+			return s, start
 		}
 	)
 
-	for {
-		line, err := r.ReadString('\n')
+	for !r.eof {
+		line, err := r.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", 0, err
+		}
+		r.eof = err == io.EOF
 		line = strings.TrimRight(line, "\n\r")
+		r.line++
 
 		if strings.HasPrefix(line, first) {
-			if err = emit(); err != nil {
-				return err
+			if r.started {
+				s, start := flush()
+				r.lines = []string{line}
+				r.start = r.line
+				return s, start, nil
 			}
-			lines = make([]string, 0, 20)
+			// Lines before the first OMM (like comments)
+			// are part of it.
+			r.started = true
 		}
-		lines = append(lines, line)
-
-		if err == io.EOF {
-			break
+		if r.lines == nil {
+			r.start = r.line
 		}
+		r.lines = append(r.lines, line)
+	}
+
+	s, start := flush()
+	if strings.TrimSpace(s) == "" {
+		return "", 0, io.EOF
+	}
+	return s, start, nil
+}
+
+func (r *kvnReader) Read() (*Elements, error) {
+	s, line, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	r.record++
+	e, _, err := parseKVN(s, line, r.opts)
+	if err != nil {
+		return nil, recordError(err, "kvn", r.record, line)
 	}
+	return e, nil
+}
+
+// kvnWriter writes OMMs in KVN format separated by blank lines.
+type kvnWriter struct {
+	w io.Writer
+}
 
-	return emit()
+func (w *kvnWriter) Write(e *Elements) error {
+	s, err := e.MarshalKVN()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w.w, s+"\n")
+	return err
+}
+
+func (w *kvnWriter) Close() error {
+	return nil
 }
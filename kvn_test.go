@@ -2,8 +2,11 @@ package gpelements
 
 import (
 	"bufio"
+	"errors"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestKVNs(t *testing.T) {
@@ -53,3 +56,121 @@ func TestParseInternationalDesignator(t *testing.T) {
 		t.Fatal(p)
 	}
 }
+
+func TestParseKVNGeneric(t *testing.T) {
+	kvn := `CCSDS_OMM_VERS = 2.0
+COMMENT this is a comment
+CREATION_DATE  = 2020-12-14T03:56:29
+ORIGINATOR     = TEST
+
+OBJECT_NAME    = GOES 9
+OBJECT_ID      = 1995-025A
+CENTER_NAME    = EARTH
+REF_FRAME      = TOD
+TIME_SYSTEM    = UTC
+MEAN_ELEMENT_THEORY = SGP/SGP4
+
+COMMENT Keywords can come in any order.
+EPOCH          = 2007-05-04T10:34:41.4264
+INCLINATION    = 3.0539 [deg]
+MEAN_MOTION    = 1.00273272 [rev/day]
+ECCENTRICITY   = 0.0005013
+RA_OF_ASC_NODE = 81.7939 [deg]
+ARG_OF_PERICENTER = 249.2363 [deg]
+MEAN_ANOMALY   = 150.1602 [deg]
+GM             = 398600.8 [km**3/s**2]
+
+MASS           = 1500.0 [kg]
+DRAG_COEFF     = 2.2
+
+EPHEMERIS_TYPE = 0
+CLASSIFICATION_TYPE = U
+NORAD_CAT_ID   = 23581
+ELEMENT_SET_NO = 0925
+REV_AT_EPOCH   = 4316
+BSTAR          = 0.0001 [1/ER]
+MEAN_MOTION_DOT = -0.00000113 [rev/day**2]
+MEAN_MOTION_DDOT = 0.0 [rev/day**3]
+
+COV_REF_FRAME  = TEME
+CX_X           = 3.331349476038534e-04
+CY_X           = 4.618927349220216e-04
+
+USER_DEFINED_EARTH_MODEL = WGS-84
+`
+	e, _, err := ParseKVN(kvn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.RefFrame != "TOD" {
+		t.Fatal(e.RefFrame)
+	}
+	if e.MeanElementTheory != "SGP/SGP4" {
+		t.Fatal(e.MeanElementTheory)
+	}
+	if e.Inclination != 3.0539 {
+		t.Fatal(e.Inclination)
+	}
+	if e.ElementSet != 925 {
+		t.Fatal(e.ElementSet)
+	}
+	if e.Originator != "TEST" {
+		t.Fatal(e.Originator)
+	}
+
+	bad := strings.Replace(kvn, "0.0005013", "0.000S013", 1)
+	if _, _, err = ParseKVN(bad); err == nil {
+		t.Fatal("expected an error")
+	} else if !strings.Contains(err.Error(), "line 17") {
+		t.Fatal(err)
+	}
+
+	// Only numeric values have units, and a COMMENT can be followed
+	// by any space.
+	odd := strings.Replace(kvn, "OBJECT_NAME    = GOES 9", "OBJECT_NAME    = GOES 9 [TEST]", 1)
+	odd = strings.Replace(odd, "COMMENT this is a comment", "COMMENT\tthis is a comment", 1)
+	odd = strings.Replace(odd, "USER_DEFINED_EARTH_MODEL = WGS-84", "USER_DEFINED_EARTH_MODEL = WGS-84\nPERIOD = 1436.1 [min]\nNOTE = a [b]", 1)
+	if e, _, err = ParseKVN(odd); err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "GOES 9 [TEST]" {
+		t.Fatal(e.Name)
+	}
+	if v, _ := e.GetExtra("PERIOD"); v != "1436.1" {
+		t.Fatal(v)
+	}
+	if v, _ := e.GetExtra("NOTE"); v != "a [b]" {
+		t.Fatal(v)
+	}
+	if !MaybeKVN([]byte("COMMENT\tfirst\n" + kvn)) {
+		t.Fatal("not KVN")
+	}
+
+	// A required keyword without a value is missing.
+	for _, v := range []string{"", "null"} {
+		empty := strings.Replace(kvn, "2007-05-04T10:34:41.4264", v, 1)
+		_, _, err := ParseKVN(empty)
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Field != "EPOCH" {
+			t.Fatalf("'%s': %v", v, err)
+		}
+	}
+}
+
+func TestKVNRoundTripCreationDate(t *testing.T) {
+	e := testElements(t)
+	created := time.Date(2020, 12, 13, 3, 44, 10, 913000000, time.UTC)
+	e.CreationDate = NewTime(created)
+
+	kvn, err := e.MarshalKVN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := ParseKVN(kvn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CreationDate == nil || !time.Time(*got.CreationDate).Equal(created) {
+		t.Fatal(got.CreationDate)
+	}
+}
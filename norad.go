@@ -12,8 +12,10 @@ import (
 // Note that JSON serializations have explicit syntax for strings vs
 // numbers, so a NoradCatId will try to (de)serialize as a number.
 //
-// This type also has support for encoding/decoding in a large base in
-// order to fit large numbers in five characters in a TLE.
+// Encode and Decode convert between a numeric NoradCatId and its TLE
+// representation, which is Alpha-5 for numbers from 100000 to
+// MaxAlpha5.  For larger numbers, they use a z-prefixed encoding in a
+// large base in order to fit the number in five characters.
 type NoradCatId string
 
 func NewNoradCatId(s string) NoradCatId {
@@ -98,15 +100,71 @@ func Unbase(s string, alphabet string) (int64, error) {
 
 var noradCatBase = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
+// alpha5Letters are the leading characters of Alpha-5 catalog
+// numbers, which skip I and O.  A is 10, so A0001 is 100001.
+const alpha5Letters = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// MaxAlpha5 is the largest catalog number that Alpha-5 can
+// represent (Z9999).
+const MaxAlpha5 = 339999
+
+// Alpha5 returns the Alpha-5 representation of a catalog number,
+// which is just the (zero-padded) number below 100000.
+func Alpha5(n int64) (string, error) {
+	if n < 0 || MaxAlpha5 < n {
+		return "", fmt.Errorf("%d isn't in Alpha-5's range", n)
+	}
+	if n < 100000 {
+		return fmt.Sprintf("%05d", n), nil
+	}
+	letter := alpha5Letters[n/10000-10]
+	return fmt.Sprintf("%c%04d", letter, n%10000), nil
+}
+
+// ParseAlpha5 parses an Alpha-5 catalog number, which can also be
+// just digits.
+func ParseAlpha5(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 || 5 < len(s) {
+		return 0, fmt.Errorf("'%s' isn't Alpha-5", s)
+	}
+	var high int64
+	if i := strings.IndexByte(alpha5Letters, s[0]); 0 <= i {
+		if len(s) != 5 {
+			return 0, fmt.Errorf("'%s' isn't Alpha-5", s)
+		}
+		high = int64(10+i) * 10000
+		s = s[1:]
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' isn't Alpha-5", s)
+	}
+	return high + int64(n), nil
+}
+
+// Encode returns the TLE representation of a numeric catalog number:
+// Alpha-5 for numbers from 100000 to MaxAlpha5 and the z-prefixed
+// encoding for larger numbers.  Other values are returned as is.
 func (s NoradCatId) Encode() NoradCatId {
 	n, err := strconv.ParseInt(string(s), 10, 64)
-	if err != nil {
+	if err != nil || n < 100000 {
 		return s
 	}
+	if a, err := Alpha5(n); err == nil {
+		return NoradCatId(a)
+	}
 	return NoradCatId(Base(n, noradCatBase))
 }
 
+// Decode returns the number for a TLE representation in Alpha-5 or
+// the z-prefixed encoding.  Other values are returned as is.
 func (s NoradCatId) Decode() NoradCatId {
+	if 0 < len(s) && strings.IndexByte(alpha5Letters, s[0]) >= 0 {
+		if n, err := ParseAlpha5(string(s)); err == nil {
+			return NoradCatId(strconv.FormatInt(n, 10))
+		}
+	}
 	d, err := Unbase(string(s), noradCatBase)
 	if err != nil {
 		return s
@@ -114,3 +172,17 @@ func (s NoradCatId) Decode() NoradCatId {
 	// ToDo: Error on overflow here?
 	return NoradCatId(strconv.FormatInt(d, 10))
 }
+
+// tle returns the TLE representation of the catalog number, which
+// must fit in five characters.  The z-prefixed encoding is only used
+// if base62 is true.
+func (s NoradCatId) tle(base62 bool) (string, error) {
+	if n, err := strconv.ParseInt(string(s), 10, 64); err == nil && MaxAlpha5 < n && !base62 {
+		return "", fmt.Errorf("catalog number %d is too big for Alpha-5", n)
+	}
+	enc := string(s.Encode())
+	if 5 < len(enc) {
+		return "", fmt.Errorf("catalog number '%s' doesn't fit in a TLE", s)
+	}
+	return enc, nil
+}
@@ -0,0 +1,106 @@
+package gpelements
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAlpha5(t *testing.T) {
+	for n, s := range map[int64]string{
+		5:      "00005",
+		25544:  "25544",
+		99999:  "99999",
+		100000: "A0000",
+		100001: "A0001",
+		179999: "H9999",
+		180000: "J0000", // No I.
+		229999: "N9999",
+		230000: "P0000", // No O.
+		339999: "Z9999",
+	} {
+		a, err := Alpha5(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a != s {
+			t.Fatalf("%d: %s != %s", n, a, s)
+		}
+		m, err := ParseAlpha5(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != n {
+			t.Fatalf("%s: %d != %d", s, m, n)
+		}
+	}
+
+	if _, err := Alpha5(MaxAlpha5 + 1); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, s := range []string{"I0000", "O1234", "A123", "A12345", "a0001", ""} {
+		if _, err := ParseAlpha5(s); err == nil {
+			t.Fatalf("expected an error for '%s'", s)
+		}
+	}
+}
+
+func TestNoradCatIdEncode(t *testing.T) {
+	for _, s := range []NoradCatId{"25544", "100001", "339999", "340000", "1234567"} {
+		enc := s.Encode()
+		if 5 < len(enc) {
+			t.Fatalf("%s: %s", s, enc)
+		}
+		if dec := enc.Decode(); dec != s {
+			t.Fatalf("%s: %s -> %s", s, enc, dec)
+		}
+	}
+	if enc := NoradCatId("100001").Encode(); enc != "A0001" {
+		t.Fatal(enc)
+	}
+}
+
+func TestTLEAlpha5(t *testing.T) {
+	lines := strings.SplitN(testTLE, "\n", 3)
+
+	alpha5 := func(line string) string {
+		line = line[0:2] + "A0001" + line[7:68]
+		return line + checksum(line)
+	}
+
+	e, err := ParseTLE(lines[0], alpha5(lines[1]), alpha5(lines[2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.NoradCatId != "100001" {
+		t.Fatal(e.NoradCatId)
+	}
+
+	js, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(js), `"NORAD_CAT_ID":100001,`) {
+		t.Fatal(string(js))
+	}
+
+	_, line1, line2, err := e.MarshalTLE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line1[2:7] != "A0001" || line2[2:7] != "A0001" {
+		t.Fatalf("\n%s\n%s", line1, line2)
+	}
+
+	e.NoradCatId = "340000"
+	if _, _, _, err = e.MarshalTLE(); err == nil {
+		t.Fatal("expected an error")
+	}
+	_, line1, _, err = e.MarshalTLEWith(MarshalOptions{Base62: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line1[2] != 'z' {
+		t.Fatal(line1)
+	}
+}
@@ -0,0 +1,109 @@
+package gpelements
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Observer is a site on the DefaultEllipsoid.
+type Observer struct {
+	// LatLonAlt is in degrees and km.
+	LatLonAlt
+}
+
+// ParseObserver parses "LAT,LON,ALT" (degrees, degrees, km).  The
+// altitude is optional.
+func ParseObserver(s string) (*Observer, error) {
+	ps := strings.Split(s, ",")
+	if len(ps) < 2 || 3 < len(ps) {
+		return nil, fmt.Errorf("bad observer '%s' (want LAT,LON,ALT)", s)
+	}
+	var xs [3]float64
+	for i, p := range ps {
+		x, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, wrapErrf(err, "bad observer '%s'", s)
+		}
+		xs[i] = x
+	}
+	if xs[0] < -90 || 90 < xs[0] {
+		return nil, fmt.Errorf("bad observer latitude %f", xs[0])
+	}
+	return &Observer{
+		LatLonAlt: LatLonAlt{
+			Lat: xs[0],
+			Lon: xs[1],
+			Alt: xs[2],
+		},
+	}, nil
+}
+
+// Look is where an object appears to an Observer.
+type Look struct {
+	At time.Time
+
+	// Az (from north through east) and El are in degrees.
+	Az, El float64
+
+	// Range is in km, and RangeRate is in km/s.
+	Range, RangeRate float64
+
+	// RA and Dec are the topocentric right ascension and
+	// declination in GCRF in degrees.
+	RA, Dec float64
+}
+
+// Look returns where the object with the (TEME) ephemeris appears.
+func (o *Observer) Look(eph Ephemeris) (*Look, error) {
+	fixed, err := eph.InFrame(ITRF)
+	if err != nil {
+		return nil, err
+	}
+	inertial, err := eph.InFrame(GCRF)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		site = DefaultEllipsoid.FromGeodetic(o.LatLonAlt).vec3()
+		rho  = fixed.ECI.vec3().sub(site)
+		rng  = rho.norm()
+
+		lat, lon = o.Lat * math.Pi / 180, o.Lon * math.Pi / 180
+		sinLat   = math.Sin(lat)
+		cosLat   = math.Cos(lat)
+		sinLon   = math.Sin(lon)
+		cosLon   = math.Cos(lon)
+
+		east  = -sinLon*rho[0] + cosLon*rho[1]
+		north = -sinLat*cosLon*rho[0] - sinLat*sinLon*rho[1] + cosLat*rho[2]
+		up    = cosLat*cosLon*rho[0] + cosLat*sinLon*rho[1] + sinLat*rho[2]
+
+		// The site's inertial position.
+		gmst, _ = TimeToGST(eph.At)
+		teme    = rotZ(gmst).transpose().mul(polarMotion(eph.At).transpose())
+		topo    = inertial.ECI.vec3().sub(temeToGCRF(eph.At).mul(teme).apply(site))
+	)
+
+	return &Look{
+		At:        eph.At,
+		Az:        degrees360(math.Atan2(east, north)),
+		El:        math.Asin(up/rng) * 180 / math.Pi,
+		Range:     rng,
+		RangeRate: rho.dot(fixed.V.vec3()) / rng,
+		RA:        degrees360(math.Atan2(topo[1], topo[0])),
+		Dec:       math.Asin(topo[2]/topo.norm()) * 180 / math.Pi,
+	}, nil
+}
+
+// degrees360 converts radians to degrees in [0,360).
+func degrees360(a float64) float64 {
+	d := math.Mod(a*180/math.Pi, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d
+}
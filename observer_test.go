@@ -0,0 +1,158 @@
+package gpelements
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// temeAt returns the TEME ephemeris of an Earth-fixed position.
+func temeAt(t time.Time, p Vect) Ephemeris {
+	gmst, _ := TimeToGST(t)
+	return Ephemeris{
+		At:    t,
+		Frame: TEME,
+		Units: EphemerisUnits,
+		ECI:   rotZ(gmst).transpose().apply(p.vec3()).Vect(),
+	}
+}
+
+func TestObserverLook(t *testing.T) {
+	var (
+		at  = time.Date(2020, 12, 16, 0, 0, 0, 0, time.UTC)
+		obs = &Observer{LatLonAlt{Lat: 40, Lon: -105, Alt: 1.6}}
+	)
+
+	for _, tc := range []struct {
+		lla    LatLonAlt
+		az, el float64
+	}{
+		// Straight up.
+		{LatLonAlt{40, -105, 501.6}, -1, 90},
+		// Far to the north and south (below the horizon).
+		{LatLonAlt{50, -105, 1.6}, 0, -1},
+		{LatLonAlt{30, -105, 1.6}, 180, -1},
+	} {
+		look, err := obs.Look(temeAt(at, DefaultEllipsoid.FromGeodetic(tc.lla)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.el == 90 {
+			if 1e-6 < math.Abs(look.El-90) || 1e-6 < math.Abs(look.Range-500) {
+				t.Fatalf("%#v", look)
+			}
+			continue
+		}
+		if 1e-6 < math.Abs(math.Remainder(look.Az-tc.az, 360)) {
+			t.Fatalf("%v: %#v", tc.lla, look)
+		}
+		if 0 < look.El {
+			t.Fatalf("%v: %#v", tc.lla, look)
+		}
+	}
+
+	// Due east and west, above and below the horizon.
+	var (
+		site     = DefaultEllipsoid.FromGeodetic(obs.LatLonAlt).vec3()
+		lat, lon = obs.Lat * math.Pi / 180, obs.Lon * math.Pi / 180
+		east     = vec3{-math.Sin(lon), math.Cos(lon), 0}
+		up       = vec3{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+	)
+	for _, tc := range []struct {
+		az, el float64
+	}{
+		{90, 10},
+		{90, -5},
+		{270, 20},
+		{270, -15},
+	} {
+		var (
+			el   = tc.el * math.Pi / 180
+			e    = math.Cos(el) * math.Sin(tc.az*math.Pi/180)
+			u    = math.Sin(el)
+			dist = 1000.0
+			p    = site
+		)
+		for i := range p {
+			p[i] += dist * (e*east[i] + u*up[i])
+		}
+		look, err := obs.Look(temeAt(at, p.Vect()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if 1e-6 < math.Abs(look.Az-tc.az) || 1e-6 < math.Abs(look.El-tc.el) {
+			t.Fatalf("%v: %#v", tc, look)
+		}
+		if (0 < look.El) != (0 < tc.el) {
+			t.Fatalf("%v: %#v", tc, look)
+		}
+	}
+
+	// Something much farther than the Earth's radius (where
+	// parallax is negligible) has about its geocentric RA and Dec.
+	far := Vect{3e11, 4e11, 5e11}
+	look, err := obs.Look(Ephemeris{At: at, Frame: TEME, Units: EphemerisUnits, ECI: far})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, _ := TEMEToGCRF(at, far, Vect{})
+	var (
+		ra  = degrees360(math.Atan2(g.Y, g.X))
+		dec = math.Asin(g.Z/g.vec3().norm()) * 180 / math.Pi
+	)
+	if 1e-4 < math.Abs(look.RA-ra) || 1e-4 < math.Abs(look.Dec-dec) {
+		t.Fatalf("%#v vs %f %f", look, ra, dec)
+	}
+}
+
+func TestObserverRangeRate(t *testing.T) {
+	e := testElements(t)
+	o, err := e.SGP4()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		obs = &Observer{LatLonAlt{Lat: 0, Lon: 170, Alt: 0}}
+		at  = time.Time(*e.Epoch).Add(10 * time.Minute).Truncate(time.Second)
+		dt  = time.Second
+	)
+	look := func(t0 time.Time) *Look {
+		s, err := Prop(o, t0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, err := obs.Look(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	var (
+		l0 = look(at.Add(-dt))
+		l  = look(at)
+		l1 = look(at.Add(dt))
+	)
+	if want := (l1.Range - l0.Range) / (2 * dt.Seconds()); 1e-4 < math.Abs(l.RangeRate-want) {
+		t.Fatalf("%f != %f", l.RangeRate, want)
+	}
+}
+
+func TestParseObserver(t *testing.T) {
+	o, err := ParseObserver("40.5, -105.25,1.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.LatLonAlt != (LatLonAlt{40.5, -105.25, 1.6}) {
+		t.Fatalf("%#v", o)
+	}
+	if o, err = ParseObserver("40,-105"); err != nil || o.Alt != 0 {
+		t.Fatal(o, err)
+	}
+	for _, s := range []string{"40", "91,0,0", "a,b,c", "1,2,3,4"} {
+		if _, err := ParseObserver(s); err == nil {
+			t.Fatalf("expected an error for '%s'", s)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package gpelements
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ommField connects an OMM keyword to an Elements field via its
+// textual representation.
+//
+// The codecs that don't get help from struct tags (JSON input, CSV,
+// KVN) use these to find fields by keyword.
+type ommField struct {
+	get func(e *Elements) string
+
+	// set is never called with an empty string.
+	set func(e *Elements, s string) error
+
+	// time is only given for time-valued fields, which some
+	// formats want to lay out themselves.
+	time func(e *Elements) **Time
+
+	// numeric says whether the value is a number.
+	numeric bool
+}
+
+var ommFields = map[string]ommField{
+	"CCSDS_OMM_VERS": stringField(func(e *Elements) *string { return &e.OMMVersion }),
+	"CREATION_DATE":  timeField(func(e *Elements) **Time { return &e.CreationDate }),
+	"ORIGINATOR":     stringField(func(e *Elements) *string { return &e.Originator }),
+	"MESSAGE_ID":     stringField(func(e *Elements) *string { return &e.MessageId }),
+
+	"OBJECT_NAME": stringField(func(e *Elements) *string { return &e.Name }),
+	"OBJECT_ID":   stringField(func(e *Elements) *string { return &e.Id }),
+
+	"CENTER_NAME":         stringField(func(e *Elements) *string { return &e.CenterName }),
+	"REF_FRAME":           stringField(func(e *Elements) *string { return &e.RefFrame }),
+	"REF_FRAME_EPOCH":     timeField(func(e *Elements) **Time { return &e.RefFrameEpoch }),
+	"TIME_SYSTEM":         stringField(func(e *Elements) *string { return &e.TimeSystem }),
+	"MEAN_ELEMENT_THEORY": stringField(func(e *Elements) *string { return &e.MeanElementTheory }),
+
+	"EPOCH":             timeField(func(e *Elements) **Time { return &e.Epoch }),
+	"MEAN_MOTION":       floatField(func(e *Elements) *float64 { return &e.MeanMotion }),
+	"ECCENTRICITY":      floatField(func(e *Elements) *float64 { return &e.Eccentricity }),
+	"INCLINATION":       floatField(func(e *Elements) *float64 { return &e.Inclination }),
+	"RA_OF_ASC_NODE":    floatField(func(e *Elements) *float64 { return &e.RightAscension }),
+	"ARG_OF_PERICENTER": floatField(func(e *Elements) *float64 { return &e.ArgOfPericenter }),
+	"MEAN_ANOMALY":      floatField(func(e *Elements) *float64 { return &e.MeanAnomaly }),
+	"GM":                floatField(func(e *Elements) *float64 { return &e.GM }),
+
+	"MASS":            spacecraftField(func(sp *SpacecraftParameters) *float64 { return &sp.Mass }),
+	"SOLAR_RAD_AREA":  spacecraftField(func(sp *SpacecraftParameters) *float64 { return &sp.SolarRadArea }),
+	"SOLAR_RAD_COEFF": spacecraftField(func(sp *SpacecraftParameters) *float64 { return &sp.SolarRadCoeff }),
+	"DRAG_AREA":       spacecraftField(func(sp *SpacecraftParameters) *float64 { return &sp.DragArea }),
+	"DRAG_COEFF":      spacecraftField(func(sp *SpacecraftParameters) *float64 { return &sp.DragCoeff }),
+
+	"EPHEMERIS_TYPE":      intField(func(e *Elements) *int { return &e.EphemerisType }),
+	"CLASSIFICATION_TYPE": stringField(func(e *Elements) *string { return &e.ClassificationType }),
+	"NORAD_CAT_ID": {
+		get: func(e *Elements) string { return string(e.NoradCatId) },
+		set: func(e *Elements, s string) error {
+			e.NoradCatId = NewNoradCatId(s)
+			return nil
+		},
+	},
+	"ELEMENT_SET_NO":   intField(func(e *Elements) *int { return &e.ElementSet }),
+	"REV_AT_EPOCH":     floatField(func(e *Elements) *float64 { return &e.RevAtEpoch }),
+	"BSTAR":            floatField(func(e *Elements) *float64 { return &e.BStar }),
+	"MEAN_MOTION_DOT":  floatField(func(e *Elements) *float64 { return &e.MeanMotionDot }),
+	"MEAN_MOTION_DDOT": floatField(func(e *Elements) *float64 { return &e.MeanMotionDDot }),
+
+	"COV_REF_FRAME": {
+		get: func(e *Elements) string {
+			if e.Covariance == nil {
+				return ""
+			}
+			return e.Covariance.CovRefFrame
+		},
+		set: func(e *Elements, s string) error {
+			if e.Covariance == nil {
+				e.Covariance = &Covariance{}
+			}
+			e.Covariance.CovRefFrame = s
+			return nil
+		},
+	},
+}
+
+func init() {
+	for i, k := range CovarianceKeywords() {
+		ommFields[k] = covarianceField(i)
+	}
+}
+
+// UserDefinedPrefix starts the keyword of each USER_DEFINED parameter.
+const UserDefinedPrefix = "USER_DEFINED_"
+
+// lookupField finds the field for an OMM keyword, including
+// USER_DEFINED_* keywords.
+func lookupField(k string) (ommField, bool) {
+	if field, have := ommFields[k]; have {
+		return field, true
+	}
+	if strings.HasPrefix(k, UserDefinedPrefix) && len(UserDefinedPrefix) < len(k) {
+		param := k[len(UserDefinedPrefix):]
+		return ommField{
+			get: func(e *Elements) string {
+				v, _ := e.GetUserDefined(param)
+				return v
+			},
+			set: func(e *Elements, s string) error {
+				e.SetUserDefined(param, s)
+				return nil
+			},
+		}, true
+	}
+	return ommField{}, false
+}
+
+// GetUserDefined returns the value of the given USER_DEFINED
+// parameter (without the "USER_DEFINED_" prefix).
+func (e *Elements) GetUserDefined(param string) (string, bool) {
+	for _, u := range e.UserDefined {
+		if u.Parameter == param {
+			return u.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetUserDefined sets (or adds) the given USER_DEFINED parameter
+// (without the "USER_DEFINED_" prefix).
+func (e *Elements) SetUserDefined(param, value string) {
+	for i, u := range e.UserDefined {
+		if u.Parameter == param {
+			e.UserDefined[i].Value = value
+			return
+		}
+	}
+	e.UserDefined = append(e.UserDefined, UserDefined{
+		Parameter: param,
+		Value:     value,
+	})
+}
+
+// ommRequired are the keywords that every OMM must have (as far as
+// we're concerned).
+var ommRequired = []string{
+	"EPOCH",
+	"MEAN_MOTION",
+	"ECCENTRICITY",
+	"INCLINATION",
+	"RA_OF_ASC_NODE",
+	"ARG_OF_PERICENTER",
+	"MEAN_ANOMALY",
+}
+
+// missingRequired returns the first of ommRequired that doesn't have
+// a value.
+func missingRequired(have map[string]bool) (string, bool) {
+	for _, k := range ommRequired {
+		if !have[k] {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// setValue sets the field and reports whether it has a value, which a
+// time set to "null" doesn't.
+func (f ommField) setValue(e *Elements, s string) (bool, error) {
+	if err := f.set(e, s); err != nil {
+		return false, err
+	}
+	if f.time != nil && *f.time(e) == nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+func stringField(p func(e *Elements) *string) ommField {
+	return ommField{
+		get: func(e *Elements) string { return *p(e) },
+		set: func(e *Elements, s string) error {
+			*p(e) = s
+			return nil
+		},
+	}
+}
+
+func floatField(p func(e *Elements) *float64) ommField {
+	return ommField{
+		numeric: true,
+		get: func(e *Elements) string {
+			return strconv.FormatFloat(*p(e), 'g', -1, 64)
+		},
+		set: func(e *Elements, s string) error {
+			x, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			*p(e) = x
+			return nil
+		},
+	}
+}
+
+// spacecraftField is a floatField for SpacecraftParameters, which
+// are made as needed.
+func spacecraftField(p func(sp *SpacecraftParameters) *float64) ommField {
+	return ommField{
+		numeric: true,
+		get: func(e *Elements) string {
+			if e.SpacecraftParameters == nil {
+				return ""
+			}
+			return strconv.FormatFloat(*p(e.SpacecraftParameters), 'g', -1, 64)
+		},
+		set: func(e *Elements, s string) error {
+			x, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			if e.SpacecraftParameters == nil {
+				e.SpacecraftParameters = &SpacecraftParameters{}
+			}
+			*p(e.SpacecraftParameters) = x
+			return nil
+		},
+	}
+}
+
+// covarianceField is a floatField for the i-th element of the
+// Covariance's lower triangle, which is made as needed.
+func covarianceField(i int) ommField {
+	return ommField{
+		numeric: true,
+		get: func(e *Elements) string {
+			if e.Covariance == nil {
+				return ""
+			}
+			return strconv.FormatFloat(*e.Covariance.lower()[i], 'g', -1, 64)
+		},
+		set: func(e *Elements, s string) error {
+			x, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			if e.Covariance == nil {
+				e.Covariance = &Covariance{}
+			}
+			*e.Covariance.lower()[i] = x
+			return nil
+		},
+	}
+}
+
+func intField(p func(e *Elements) *int) ommField {
+	return ommField{
+		numeric: true,
+		get:     func(e *Elements) string { return strconv.Itoa(*p(e)) },
+		set: func(e *Elements, s string) error {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			*p(e) = n
+			return nil
+		},
+	}
+}
+
+// timeField treats "null" as a missing time.
+func timeField(p func(e *Elements) **Time) ommField {
+	return ommField{
+		get: func(e *Elements) string {
+			if t := *p(e); t != nil {
+				return t.Format(KVNTimeFormat)
+			}
+			return ""
+		},
+		set: func(e *Elements, s string) error {
+			t, err := ParseAnyTime(s)
+			if err != nil {
+				return err
+			}
+			if t.IsZero() { // "null"
+				*p(e) = nil
+				return nil
+			}
+			t0 := Time(t)
+			*p(e) = &t0
+			return nil
+		},
+		time: p,
+	}
+}
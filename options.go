@@ -0,0 +1,134 @@
+package gpelements
+
+import (
+	"fmt"
+	"time"
+)
+
+// Strictness says how picky parsing is about problems that don't
+// prevent a parse.
+type Strictness int
+
+const (
+	// Standard warns about bad TLE checksums and malformed
+	// international designators and pads short TLE lines.
+	Standard Strictness = iota
+
+	// Strict rejects bad TLE checksums, short TLE lines, and
+	// malformed international designators.
+	Strict
+
+	// Lenient ignores bad TLE checksums, pads short TLE lines,
+	// and warns about malformed international designators.
+	Lenient
+)
+
+func (s Strictness) String() string {
+	switch s {
+	case Standard:
+		return "standard"
+	case Strict:
+		return "strict"
+	case Lenient:
+		return "lenient"
+	default:
+		return fmt.Sprintf("Strictness(%d)", int(s))
+	}
+}
+
+// ParseStrictness parses "strict", "standard", or "lenient".
+func ParseStrictness(s string) (Strictness, error) {
+	for _, x := range []Strictness{Standard, Strict, Lenient} {
+		if s == x.String() {
+			return x, nil
+		}
+	}
+	return Standard, fmt.Errorf("unknown strictness '%s'", s)
+}
+
+// ParseOptions control parsing.
+type ParseOptions struct {
+	Strictness Strictness
+
+	// Years expands the two-digit years of a TLE.
+	Years YearWindow
+}
+
+// DefaultParseOptions are used by functions that don't take
+// ParseOptions (like ParseTLE and Do).
+var DefaultParseOptions = ParseOptions{}
+
+// problem reports a problem that's an error if strict and otherwise
+// maybe a warning (added to the Elements).
+func (o ParseOptions) problem(e *Elements, warn bool, err error) error {
+	if o.Strictness == Strict {
+		return err
+	}
+	if warn {
+		e.Warnings = append(e.Warnings, err.Error())
+	}
+	return nil
+}
+
+// MarshalOptions control marshaling.
+type MarshalOptions struct {
+	// Base62 allows catalog numbers beyond Alpha-5's range (above
+	// MaxAlpha5) in a TLE by using a z-prefixed encoding in base
+	// 62, which other software won't understand.
+	Base62 bool
+
+	// Years says which years a TLE's two-digit years can
+	// represent.  Other years are an error.
+	Years YearWindow
+}
+
+// DefaultMarshalOptions are used by functions that don't take
+// MarshalOptions (like MarshalTLE).
+var DefaultMarshalOptions = MarshalOptions{}
+
+// DefaultYearStart is the first year of the zero YearWindow, which
+// is the traditional TLE window: "56" is 1956 and "55" is 2055.
+const DefaultYearStart = 1956
+
+// YearWindow is the 100 years, starting with Start, that two-digit
+// years represent.  A zero Start means DefaultYearStart.
+type YearWindow struct {
+	Start int
+}
+
+// SlidingYearWindow returns the window that ends the given number of
+// years after the year of t.
+func SlidingYearWindow(t time.Time, ahead int) YearWindow {
+	return YearWindow{
+		Start: t.UTC().Year() + ahead - 99,
+	}
+}
+
+func (w YearWindow) start() int {
+	if w.Start == 0 {
+		return DefaultYearStart
+	}
+	return w.Start
+}
+
+// Expand returns the year in the window with the given two digits.
+func (w YearWindow) Expand(yy int) int {
+	var (
+		start = w.start()
+		year  = start - start%100 + yy
+	)
+	if year < start {
+		year += 100
+	}
+	return year
+}
+
+// TwoDigits returns the last two digits of the year or an error if
+// the year isn't in the window.
+func (w YearWindow) TwoDigits(year int) (int, error) {
+	start := w.start()
+	if year < start || start+100 <= year {
+		return 0, fmt.Errorf("year %d isn't in %d-%d", year, start, start+99)
+	}
+	return year % 100, nil
+}
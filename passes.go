@@ -0,0 +1,325 @@
+package gpelements
+
+import (
+	"fmt"
+	"time"
+
+	sgp4 "github.com/morphism/sgp4go"
+)
+
+// Pass is a time when an object is above an Observer's elevation
+// mask.
+type Pass struct {
+	// AOS (acquisition of signal) is when the object rises above
+	// the mask or the start of the search if it's already above.
+	AOS Look
+
+	// TCA (time of closest approach) is the maximum elevation.
+	TCA Look
+
+	// LOS (loss of signal) is when the object sets below the mask
+	// or the end of the search if it's still above.
+	LOS Look
+
+	Duration time.Duration
+
+	// Quality is a rating of the maximum elevation: "excellent" (at
+	// least 60 degrees), "good" (30), "fair" (15), or "poor".
+	Quality string
+}
+
+// PassOptions control Passes.
+type PassOptions struct {
+	// Mask is the minimum elevation in degrees.
+	Mask float64
+
+	// Step is the interval that brackets rises, sets, and peaks
+	// before they're refined.  The default is a sixtieth of the
+	// orbital period but at most a minute.
+	Step time.Duration
+
+	// Precision is how exact the times are.  The default is 10 ms.
+	Precision time.Duration
+}
+
+// passQuality rates a pass's maximum elevation.
+func passQuality(el float64) string {
+	switch {
+	case 60 <= el:
+		return "excellent"
+	case 30 <= el:
+		return "good"
+	case 15 <= el:
+		return "fair"
+	default:
+		return "poor"
+	}
+}
+
+// Passes finds the passes of the object over the observer from one
+// time to another.
+//
+// Rises and sets are found by bisection on the elevation, and the
+// maximum elevation is found by golden-section search.  Sampling at
+// PassOptions.Step only brackets them; a peak between samples that
+// both are below the mask is still found.
+func (e *Elements) Passes(o *Observer, from, to time.Time, opts PassOptions) ([]Pass, error) {
+	if e.Epoch == nil {
+		return nil, fmt.Errorf("no epoch")
+	}
+	prop, err := e.SGP4()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Step, opts.Precision = e.searchDefaults(opts.Step, opts.Precision, 60)
+
+	f := &passFinder{
+		o:    o,
+		prop: prop,
+		opts: opts,
+	}
+	return f.find(from, to)
+}
+
+// searchDefaults fills in a search's step, which defaults to the
+// orbital period divided by perPeriod but at most a minute, and
+// precision, which defaults to 10 ms.
+func (e *Elements) searchDefaults(step, precision time.Duration, perPeriod int) (time.Duration, time.Duration) {
+	if step <= 0 {
+		step = time.Minute
+		if 0 < e.MeanMotion {
+			period := time.Duration(float64(24*time.Hour) / e.MeanMotion)
+			if p := period / time.Duration(perPeriod); p < step {
+				step = p
+			}
+		}
+	}
+	if precision <= 0 {
+		precision = 10 * time.Millisecond
+	}
+	return step, precision
+}
+
+type passFinder struct {
+	o    *Observer
+	prop *sgp4.TLE
+	opts PassOptions
+}
+
+func (f *passFinder) look(t time.Time) (*Look, error) {
+	s, err := Prop(f.prop, t)
+	if err != nil {
+		return nil, err
+	}
+	return f.o.Look(s)
+}
+
+// above returns the elevation above the mask.
+func (f *passFinder) above(t time.Time) (float64, error) {
+	l, err := f.look(t)
+	if err != nil {
+		return 0, err
+	}
+	return l.El - f.opts.Mask, nil
+}
+
+// root finds when the elevation crosses the mask between a and b,
+// where the elevation is above the mask at a if rising is false and
+// at b if rising is true.
+func (f *passFinder) root(a, b time.Time, rising bool) (time.Time, error) {
+	for f.opts.Precision < b.Sub(a) {
+		m := a.Add(b.Sub(a) / 2)
+		x, err := f.above(m)
+		if err != nil {
+			return m, err
+		}
+		if (0 <= x) == rising {
+			b = m
+		} else {
+			a = m
+		}
+	}
+	return a.Add(b.Sub(a) / 2), nil
+}
+
+// peak finds the maximum elevation between a and b.
+func (f *passFinder) peak(a, b time.Time) (time.Time, float64, error) {
+	const phi = 0.6180339887498949
+	var (
+		span = float64(b.Sub(a))
+		c    = a.Add(time.Duration((1 - phi) * span))
+		d    = a.Add(time.Duration(phi * span))
+	)
+	fc, err := f.above(c)
+	if err != nil {
+		return c, 0, err
+	}
+	fd, err := f.above(d)
+	if err != nil {
+		return d, 0, err
+	}
+	for f.opts.Precision < b.Sub(a) {
+		if fd < fc {
+			b, d, fd = d, c, fc
+			c = a.Add(time.Duration((1 - phi) * float64(b.Sub(a))))
+			if fc, err = f.above(c); err != nil {
+				return c, 0, err
+			}
+		} else {
+			a, c, fc = c, d, fd
+			d = a.Add(time.Duration(phi * float64(b.Sub(a))))
+			if fd, err = f.above(d); err != nil {
+				return d, 0, err
+			}
+		}
+	}
+	m := a.Add(b.Sub(a) / 2)
+	x, err := f.above(m)
+	return m, x, err
+}
+
+func (f *passFinder) find(from, to time.Time) ([]Pass, error) {
+	if !from.Before(to) {
+		return nil, nil
+	}
+
+	// Sample.
+	var (
+		ts []time.Time
+		xs []float64
+	)
+	for t := from; ; t = t.Add(f.opts.Step) {
+		if to.Before(t) {
+			t = to
+		}
+		x, err := f.above(t)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+		xs = append(xs, x)
+		if !t.Before(to) {
+			break
+		}
+	}
+
+	// Find the start and end of each pass.
+	type span struct {
+		aos, los time.Time
+	}
+	var (
+		spans []span
+		start *time.Time
+	)
+	if 0 <= xs[0] {
+		start = &ts[0]
+	}
+	for i := 1; i < len(ts); i++ {
+		var (
+			a, b   = ts[i-1], ts[i]
+			xa, xb = xs[i-1], xs[i]
+		)
+		switch {
+		case xa < 0 && 0 <= xb:
+			t, err := f.root(a, b, true)
+			if err != nil {
+				return nil, err
+			}
+			start = &t
+		case 0 <= xa && xb < 0:
+			t, err := f.root(a, b, false)
+			if err != nil {
+				return nil, err
+			}
+			spans = append(spans, span{*start, t})
+			start = nil
+		case xa < 0 && xb < 0:
+			// A peak below the mask at the samples might
+			// be above it between them.
+			var lo, hi time.Time
+			switch {
+			case i+1 < len(ts) && xa <= xb && xs[i+1] <= xb:
+				lo, hi = a, ts[i+1]
+			case i == 1 && xb < xa:
+				// Falling from the start.
+				lo, hi = a, b
+			case i+1 == len(ts) && xa < xb:
+				// Rising at the end.
+				lo, hi = a, b
+			default:
+				continue
+			}
+			t, x, err := f.peak(lo, hi)
+			if err != nil {
+				return nil, err
+			}
+			if x < 0 {
+				continue
+			}
+			aos, err := f.root(lo, t, true)
+			if err != nil {
+				return nil, err
+			}
+			los, err := f.root(t, hi, false)
+			if err != nil {
+				return nil, err
+			}
+			spans = append(spans, span{aos, los})
+		}
+	}
+	if start != nil {
+		spans = append(spans, span{*start, to})
+	}
+
+	// Describe each pass.
+	passes := make([]Pass, 0, len(spans))
+	for _, s := range spans {
+		// The highest sample in the pass brackets the peak.
+		var (
+			a, b = s.aos, s.los
+			best = -1
+		)
+		for i, t := range ts {
+			if t.Before(s.aos) || s.los.Before(t) {
+				continue
+			}
+			if best < 0 || xs[best] < xs[i] {
+				best = i
+			}
+		}
+		if 0 <= best {
+			if t := ts[best].Add(-f.opts.Step); a.Before(t) {
+				a = t
+			}
+			if t := ts[best].Add(f.opts.Step); t.Before(b) {
+				b = t
+			}
+		}
+		tca, _, err := f.peak(a, b)
+		if err != nil {
+			return nil, err
+		}
+
+		var p Pass
+		for _, x := range []struct {
+			t    time.Time
+			look *Look
+		}{
+			{s.aos, &p.AOS},
+			{tca, &p.TCA},
+			{s.los, &p.LOS},
+		} {
+			l, err := f.look(x.t)
+			if err != nil {
+				return nil, err
+			}
+			*x.look = *l
+		}
+		p.Duration = s.los.Sub(s.aos)
+		p.Quality = passQuality(p.TCA.El)
+		passes = append(passes, p)
+	}
+
+	return passes, nil
+}
@@ -0,0 +1,169 @@
+package gpelements
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPasses(t *testing.T) {
+	e := testElements(t)
+	o, err := e.SGP4()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		obs  = &Observer{LatLonAlt{Lat: 40, Lon: -105, Alt: 1.6}}
+		from = time.Time(*e.Epoch)
+		to   = from.Add(24 * time.Hour)
+		mask = 10.0
+	)
+
+	passes, err := e.Passes(obs, from, to, PassOptions{Mask: mask})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passes) == 0 {
+		t.Fatal("no passes")
+	}
+
+	el := func(at time.Time) float64 {
+		s, err := Prop(o, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, err := obs.Look(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l.El
+	}
+
+	for _, p := range passes {
+		if 0.01 < math.Abs(p.AOS.El-mask) || 0.01 < math.Abs(p.LOS.El-mask) {
+			t.Fatalf("%#v", p)
+		}
+		if p.TCA.At.Before(p.AOS.At) || p.LOS.At.Before(p.TCA.At) {
+			t.Fatalf("%#v", p)
+		}
+		if p.Duration != p.LOS.At.Sub(p.AOS.At) || p.Quality != passQuality(p.TCA.El) {
+			t.Fatalf("%#v", p)
+		}
+		for at := p.AOS.At; at.Before(p.LOS.At); at = at.Add(time.Second) {
+			if x := el(at); p.TCA.El+0.001 < x {
+				t.Fatalf("%f at %s is above %#v", x, at, p.TCA)
+			}
+		}
+	}
+
+	// Every time it's above the mask is in a pass.
+	for at := from; at.Before(to); at = at.Add(10 * time.Second) {
+		if el(at) < mask {
+			continue
+		}
+		in := false
+		for _, p := range passes {
+			if !at.Before(p.AOS.At) && !p.LOS.At.Before(at) {
+				in = true
+			}
+		}
+		if !in {
+			t.Fatalf("%s isn't in a pass", at)
+		}
+	}
+}
+
+func TestPassesClipped(t *testing.T) {
+	e := testElements(t)
+
+	obs := &Observer{LatLonAlt{Lat: 40, Lon: -105, Alt: 1.6}}
+	from := time.Time(*e.Epoch)
+	passes, err := e.Passes(obs, from, from.Add(24*time.Hour), PassOptions{Mask: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := passes[0]
+
+	// Start in the middle of the first pass.
+	mid := p.AOS.At.Add(p.Duration / 2)
+	clipped, err := e.Passes(obs, mid, p.LOS.At.Add(time.Minute), PassOptions{Mask: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clipped) != 1 || !clipped[0].AOS.At.Equal(mid) {
+		t.Fatalf("%#v", clipped)
+	}
+	if d := clipped[0].LOS.At.Sub(p.LOS.At); d < -time.Second || time.Second < d {
+		t.Fatalf("%s != %s", clipped[0].LOS.At, p.LOS.At)
+	}
+}
+
+func TestPassesShortAtEdges(t *testing.T) {
+	e := testElements(t)
+
+	var (
+		obs  = &Observer{LatLonAlt{Lat: 40, Lon: -105, Alt: 1.6}}
+		from = time.Time(*e.Epoch)
+	)
+	passes, err := e.Passes(obs, from, from.Add(24*time.Hour), PassOptions{Mask: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passes) == 0 {
+		t.Fatal("no passes")
+	}
+
+	// A mask just below the highest elevation gives a pass that's
+	// shorter than the default step.
+	best := passes[0]
+	for _, p := range passes {
+		if best.TCA.El < p.TCA.El {
+			best = p
+		}
+	}
+	opts := PassOptions{Mask: best.TCA.El - 0.5}
+	ref, err := e.Passes(obs, best.TCA.At.Add(-10*time.Minute), best.TCA.At.Add(10*time.Minute),
+		PassOptions{Mask: opts.Mask, Step: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ref) != 1 {
+		t.Fatalf("%#v", ref)
+	}
+	short := ref[0]
+	if time.Minute < short.Duration {
+		t.Fatal(short.Duration)
+	}
+
+	near := func(a, b time.Time) bool {
+		d := a.Sub(b)
+		return -100*time.Millisecond < d && d < 100*time.Millisecond
+	}
+
+	for _, w := range []struct {
+		name     string
+		from, to time.Time
+		step     time.Duration
+	}{
+		{"starts just before", short.AOS.At.Add(-5 * time.Second), short.AOS.At.Add(2 * time.Hour), 0},
+		{"ends just after", short.LOS.At.Add(-2 * time.Hour), short.LOS.At.Add(5 * time.Second), 0},
+		{"only two samples", short.AOS.At.Add(-5 * time.Second), short.LOS.At.Add(5 * time.Second), time.Hour},
+	} {
+		o := opts
+		o.Step = w.step
+		got, err := e.Passes(obs, w.from, w.to, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, p := range got {
+			if near(p.AOS.At, short.AOS.At) && near(p.LOS.At, short.LOS.At) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("%s: %#v", w.name, got)
+		}
+	}
+}
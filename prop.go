@@ -38,31 +38,52 @@ func (e *Elements) SGP4() (*sgp4.TLE, error) {
 
 // Vect is a 3-vector.
 type Vect struct {
-	X, Y, Z float32
+	X, Y, Z float64
 }
 
-// Ephemeris represents position and velocity.
+// EphemerisUnits are the units of an Ephemeris's position and
+// velocity.
+const EphemerisUnits = "km,km/s"
+
+// Ephemeris represents position and velocity at a time.
 type Ephemeris struct {
+	// At is the (UTC) time of the state.
+	At time.Time
+
+	// Frame is the frame of the position and velocity.
+	Frame Frame
+
+	// Units is EphemerisUnits.
+	Units string
+
 	// V is velocity.
 	V Vect
 
-	// C is Cartesian position.
+	// ECI is Cartesian position (in Frame, which isn't always
+	// inertial).
 	ECI Vect
 }
 
+// Prop returns the TEME state at the time.
 func Prop(o *sgp4.TLE, t time.Time) (Ephemeris, error) {
 	p, v, err := o.PropUnixMillis(t.UnixNano() / 1000 / 1000)
 	var e Ephemeris
 	if err == nil {
 		e = Ephemeris{
-			ECI: Vect{float32(p[0]), float32(p[1]), float32(p[2])},
-			V:   Vect{float32(v[0]), float32(v[1]), float32(v[2])},
+			At:    t,
+			Frame: TEME,
+			Units: EphemerisUnits,
+			ECI:   Vect{p[0], p[1], p[2]},
+			V:     Vect{v[0], v[1], v[2]},
 		}
 	}
 	return e, err
 }
 
+// TimeToGST returns Greenwich sidereal time (in radians) and the UT1
+// Julian date for the UTC time.
 func TimeToGST(t time.Time) (float64, float64) {
+	t = t.UTC().Add(UT1MinusUTC(t))
 	var (
 		y   = t.Year()
 		m   = int(t.Month())
@@ -76,31 +97,15 @@ func TimeToGST(t time.Time) (float64, float64) {
 	return sat.GSTimeFromDateNano(y, m, d, h, min, sec, ns)
 }
 
+// LatLonAlt is a geodetic position in degrees and km.
 type LatLonAlt struct {
-	Lat, Lon, Alt float32
+	Lat, Lon, Alt float64
 }
 
+// ECIToLLA converts a TEME position at the UTC time to geodetic
+// coordinates on the DefaultEllipsoid.  Polar motion is ignored.
 func ECIToLLA(t time.Time, p Vect) (*LatLonAlt, error) {
-
 	gmst, _ := TimeToGST(t)
-
-	x := sat.Vector3{
-		X: float64(p.X),
-		Y: float64(p.Y),
-		Z: float64(p.Z),
-	}
-
-	// sat.ECIToLLA is very slow.
-	alt, _, ll := sat.ECIToLLA(x, gmst)
-
-	d, err := sat.LatLongDeg(ll)
-	if err != nil {
-		return nil, err
-	}
-
-	return &LatLonAlt{
-		Lat: float32(d.Latitude),
-		Lon: float32(d.Longitude),
-		Alt: float32(alt),
-	}, nil
+	lla := DefaultEllipsoid.ToGeodetic(rotZ(gmst).apply(p.vec3()).Vect())
+	return &lla, nil
 }
@@ -19,7 +19,7 @@ func TestProp(t *testing.T) {
 	var (
 		r = bufio.NewReader(in)
 
-		now = time.Now().UTC() // ToDo: Use a constant.
+		now = time.Date(2020, 12, 16, 0, 0, 0, 0, time.UTC) // Near data/test.tle epochs.
 
 		errs  = make([]error, 0, 32)
 		count = 0
@@ -73,3 +73,27 @@ func TestProp(t *testing.T) {
 	}
 
 }
+
+func TestPropPrecision(t *testing.T) {
+	e := testElements(t)
+	o, err := e.SGP4()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Time(*e.Epoch).Add(time.Hour)
+	s, err := Prop(o, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, v, err := o.PropUnixMillis(at.UnixNano() / 1000 / 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ECI != (Vect{p[0], p[1], p[2]}) || s.V != (Vect{v[0], v[1], v[2]}) {
+		t.Fatalf("%#v", s)
+	}
+	if !s.At.Equal(at) || s.Frame != TEME || s.Units != EphemerisUnits {
+		t.Fatalf("%#v", s)
+	}
+}
@@ -0,0 +1,106 @@
+package gpelements
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encoder writes element sets in a representation as they're given.
+//
+// Any header or wrapper (like the "[" and "]" of a JSON array or the
+// <ndm> element of XML) is written as needed, so element sets aren't
+// buffered.
+type Encoder struct {
+	w      ElementsWriter
+	closed bool
+}
+
+// NewEncoder makes an Encoder for the named codec (like "csvh" or
+// "jsonarray") that marshals with the given options.
+func NewEncoder(w io.Writer, format string, opts MarshalOptions) (*Encoder, error) {
+	c, have := LookupCodec(format)
+	if !have {
+		return nil, fmt.Errorf("unknown output representation '%s'", format)
+	}
+	return &Encoder{
+		w: c.NewWriter(w, opts),
+	}, nil
+}
+
+// ErrEncoderClosed is returned by Encode after Close.
+var ErrEncoderClosed = errors.New("encoder is closed")
+
+// Encode writes an element set.
+func (enc *Encoder) Encode(e *Elements) error {
+	if enc.closed {
+		return ErrEncoderClosed
+	}
+	return enc.w.Write(e)
+}
+
+// Close finishes the output (writing any footer) but doesn't close
+// the underlying io.Writer.  Calling Close again does nothing.
+func (enc *Encoder) Close() error {
+	if enc.closed {
+		return nil
+	}
+	enc.closed = true
+	return enc.w.Close()
+}
+
+// Decoder reads element sets one at a time.
+//
+//	d := NewDecoder(r, DecodeOptions{})
+//	for d.Next() {
+//		e := d.Elements()
+//		...
+//	}
+//	if err := d.Err(); err != nil {
+//		...
+//	}
+type Decoder struct {
+	r    ElementsReader
+	e    *Elements
+	err  error
+	done bool
+}
+
+// NewDecoder makes a Decoder for the input, which is decompressed
+// and sniffed as DoWith does.
+func NewDecoder(r io.Reader, opts DecodeOptions) *Decoder {
+	er, err := newElementsReader(r, opts)
+	return &Decoder{
+		r:   er,
+		err: err,
+	}
+}
+
+// Next reads the next element set, which Elements then returns.
+// Next returns false at the end of the input or after an error.
+func (d *Decoder) Next() bool {
+	d.e = nil
+	if d.err != nil || d.done {
+		return false
+	}
+	e, err := d.r.Read()
+	if err != nil {
+		d.done = true
+		if err != io.EOF {
+			d.err = err
+		}
+		return false
+	}
+	d.e = e
+	return true
+}
+
+// Elements returns the element set that Next read.
+func (d *Decoder) Elements() *Elements {
+	return d.e
+}
+
+// Err returns the error, if any, that stopped Next.
+func (d *Decoder) Err() error {
+	return d.err
+}
@@ -0,0 +1,124 @@
+package gpelements
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	in, err := os.Open("data/test.tle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close() // Ignore error.
+
+	var (
+		buf bytes.Buffer
+		d   = NewDecoder(in, DecodeOptions{})
+		n   = 0
+	)
+	enc, err := NewEncoder(&buf, "jsonarray", MarshalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for d.Next() {
+		if err = enc.Encode(d.Elements()); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if err = d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if d.Next() {
+		t.Fatal("Next after the end")
+	}
+	if err = enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = enc.Encode(NewElements()); err != ErrEncoderClosed {
+		t.Fatal(err)
+	}
+
+	if s := strings.TrimSpace(buf.String()); !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		t.Fatal(s)
+	}
+
+	d = NewDecoder(&buf, DecodeOptions{Format: "jsonarray"})
+	m := 0
+	for d.Next() {
+		m++
+	}
+	if err = d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if m != n {
+		t.Fatalf("%d != %d", m, n)
+	}
+}
+
+func TestEncoderEmpty(t *testing.T) {
+	for format, want := range map[string]string{
+		"jsonarray": "[]\n",
+		"xml":       "<ndm>\n</ndm>\n",
+		"csv":       "",
+	} {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(&buf, format, MarshalOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Fatalf("%s: %q", format, buf.String())
+		}
+	}
+
+	if _, err := NewEncoder(&bytes.Buffer{}, "nope", MarshalOptions{}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDecoderBadFormat(t *testing.T) {
+	d := NewDecoder(strings.NewReader(testTLE), DecodeOptions{Format: "nope"})
+	if d.Next() {
+		t.Fatal("Next")
+	}
+	if d.Err() == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestEncoderMarshalOptions(t *testing.T) {
+	e := testElements(t)
+	e.NoradCatId = "340000"
+
+	var buf bytes.Buffer
+	enc, err := NewEncoder(&buf, "tle", MarshalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = enc.Encode(e); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if enc, err = NewEncoder(&buf, "tle", MarshalOptions{Base62: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = enc.Encode(e); err != nil {
+		t.Fatal(err)
+	}
+	if err = enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(buf.String(), "\n"); len(got) < 2 || got[1][2] != 'z' {
+		t.Fatal(buf.String())
+	}
+}
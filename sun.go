@@ -0,0 +1,95 @@
+package gpelements
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// AU is the astronomical unit in km.
+	AU = 149597870.7
+
+	// SunRadius is the Sun's (nominal) radius in km.
+	SunRadius = 695700.0
+)
+
+// eclipticOfDate returns the position (km) with the ecliptic longitude
+// and latitude (degrees) in the mean equator and equinox of date.
+func eclipticOfDate(T, lon, lat, r float64) vec3 {
+	var (
+		eps     = (23.439291 - 0.0130042*T) * math.Pi / 180
+		sinEps  = math.Sin(eps)
+		cosEps  = math.Cos(eps)
+		sinLon  = math.Sin(lon * math.Pi / 180)
+		cosLon  = math.Cos(lon * math.Pi / 180)
+		sinLat  = math.Sin(lat * math.Pi / 180)
+		cosLat  = math.Cos(lat * math.Pi / 180)
+		x, y, z = r * cosLat * cosLon, r * cosLat * sinLon, r * sinLat
+		mod     = vec3{x, cosEps*y - sinEps*z, sinEps*y + cosEps*z}
+	)
+	return precession(T).transpose().apply(mod)
+}
+
+// SunPosition returns the geocentric position (km) of the Sun in GCRF
+// at the UTC time.
+//
+// This is the Astronomical Almanac's low-precision formula (as given
+// by Vallado), which is good to about 0.01 degree from 1950 to 2050.
+func SunPosition(t time.Time) Vect {
+	var (
+		T  = ttCenturies(t)
+		L  = 280.460 + 36000.771*T
+		M  = (357.5291092 + 35999.05034*T) * math.Pi / 180
+		lo = L + 1.914666471*math.Sin(M) + 0.019994643*math.Sin(2*M)
+		r  = 1.000140612 - 0.016708617*math.Cos(M) - 0.000139589*math.Cos(2*M)
+	)
+	return eclipticOfDate(T, lo, 0, r*AU).Vect()
+}
+
+// MoonPosition returns the geocentric position (km) of the Moon in
+// GCRF at the UTC time.
+//
+// This is the Astronomical Almanac's low-precision formula (as given
+// by Vallado), which is good to about 0.3 degree and 0.2 Earth radii.
+func MoonPosition(t time.Time) Vect {
+	var (
+		T   = ttCenturies(t)
+		sin = func(deg float64) float64 {
+			return math.Sin(deg * math.Pi / 180)
+		}
+		cos = func(deg float64) float64 {
+			return math.Cos(deg * math.Pi / 180)
+		}
+		lon = 218.32 + 481267.8813*T +
+			6.29*sin(134.9+477198.85*T) -
+			1.27*sin(259.2-413335.38*T) +
+			0.66*sin(235.7+890534.23*T) +
+			0.21*sin(269.9+954397.70*T) -
+			0.19*sin(357.5+35999.05*T) -
+			0.11*sin(186.6+966404.05*T)
+		lat = 5.13*sin(93.3+483202.03*T) +
+			0.28*sin(228.2+960400.87*T) -
+			0.28*sin(318.3+6003.18*T) -
+			0.17*sin(217.6-407332.20*T)
+		parallax = 0.9508 +
+			0.0518*cos(134.9+477198.85*T) +
+			0.0095*cos(259.2-413335.38*T) +
+			0.0078*cos(235.7+890534.23*T) +
+			0.0028*cos(269.9+954397.70*T)
+		r = WGS84.A / sin(parallax)
+	)
+	return eclipticOfDate(T, lon, lat, r).Vect()
+}
+
+// sunIn returns the Sun's position in the inertial frame.
+func sunIn(f Frame, t time.Time) (vec3, error) {
+	sun := SunPosition(t).vec3()
+	switch f {
+	case GCRF:
+		return sun, nil
+	case TEME:
+		return temeToGCRF(t).transpose().apply(sun), nil
+	}
+	return sun, fmt.Errorf("frame %s isn't inertial", f)
+}
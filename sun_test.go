@@ -0,0 +1,67 @@
+package gpelements
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// angle returns the angle between the vectors in degrees.
+func angle(v, w vec3) float64 {
+	return math.Acos(clamp1(v.dot(w)/(v.norm()*w.norm()))) * 180 / math.Pi
+}
+
+func TestSunPosition(t *testing.T) {
+	// Vallado, "Fundamentals of Astrodynamics and Applications",
+	// example 5-1 (in the mean equator and equinox of date).
+	var (
+		at   = time.Date(2006, 4, 2, 0, 0, 0, 0, time.UTC)
+		want = vec3{146186178, 28789122, 12481127}
+		got  = precession(ttCenturies(at)).apply(SunPosition(at).vec3())
+	)
+	if a := angle(got, want); 0.001 < a {
+		t.Fatalf("%v is %f degrees from %v", got, a, want)
+	}
+	if d := math.Abs(got.norm() - want.norm()); 1000 < d {
+		t.Fatalf("%v is %f km from %v", got, d, want)
+	}
+}
+
+func TestMoonPosition(t *testing.T) {
+	// Vallado example 5-3.
+	var (
+		at   = time.Date(1994, 4, 28, 0, 0, 0, 0, time.UTC)
+		want = vec3{-134240.626, -311571.590, -126693.785}
+		got  = precession(ttCenturies(at)).apply(MoonPosition(at).vec3())
+	)
+	if a := angle(got, want); 0.05 < a {
+		t.Fatalf("%v is %f degrees from %v", got, a, want)
+	}
+	if d := math.Abs(got.norm() - want.norm()); 100 < d {
+		t.Fatalf("%v is %f km from %v", got, d, want)
+	}
+}
+
+func TestSunIn(t *testing.T) {
+	at := time.Date(2020, 12, 21, 10, 2, 0, 0, time.UTC)
+	if _, err := sunIn(ITRF, at); err == nil {
+		t.Fatal("should have complained")
+	}
+	gcrf, err := sunIn(GCRF, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teme, err := sunIn(TEME, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := angle(temeToGCRF(at).apply(teme), gcrf); 1e-5 < a {
+		t.Fatal(a)
+	}
+
+	// Near the December solstice, the Sun's declination is about
+	// -23.44 degrees.
+	if dec := math.Asin(gcrf[2]/gcrf.norm()) * 180 / math.Pi; 0.01 < math.Abs(dec+23.44) {
+		t.Fatal(dec)
+	}
+}
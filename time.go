@@ -3,6 +3,7 @@ package gpelements
 import (
 	"encoding/xml"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -24,8 +25,9 @@ func (t *Time) String() string {
 	return time.Time(*t).String()
 }
 
-func ParseTime(s, layout string) (*Time, error) {
-	t, err := time.Parse(s, layout)
+// ParseTime parses s according to the layout (as time.Parse does).
+func ParseTime(layout, s string) (*Time, error) {
+	t, err := time.Parse(layout, s)
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +46,7 @@ func (c *Time) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 		s = time.Time(*c).Format(KVNTimeFormat)
 	}
 	tokens = append(tokens, xml.CharData(s))
-	tokens = append(tokens, xml.EndElement{start.Name})
+	tokens = append(tokens, xml.EndElement{Name: start.Name})
 	for _, t := range tokens {
 		if err := e.EncodeToken(t); err != nil {
 			return err
@@ -55,21 +57,22 @@ func (c *Time) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 func (c *Time) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	var v string
-	d.DecodeElement(&v, &start)
-	if len(v) == 0 {
-		return nil
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
 	}
-	parse, err := time.Parse(KVNTimeFormat, v)
+	t, err := ParseAnyTime(v)
 	if err != nil {
 		return err
 	}
-	*c = Time(parse)
+	*c = Time(t)
 	return nil
 }
 
+// UnmarshalJSON accepts what ParseAnyTime does.  Null gives the zero
+// time.
 func (c *Time) UnmarshalJSON(data []byte) error {
 	s := strings.Trim(string(data), `"`)
-	t, err := time.Parse(KVNTimeFormat, s)
+	t, err := ParseAnyTime(s)
 	if err != nil {
 		return err
 	}
@@ -84,3 +87,195 @@ func (c *Time) MarshalJSON() ([]byte, error) {
 	s := fmt.Sprintf(`"%s"`, time.Time(*c).Format(KVNTimeFormat))
 	return []byte(s), nil
 }
+
+// ParseAnyTime parses a time in any of these forms:
+//
+//	2020-12-13T03:44:10.913
+//	2020-348T03:44:10.913    (day of the year)
+//	2020-12-13 03:44
+//	2020-12-13
+//
+// followed by an optional zone ("Z", "UTC", "+02:00", "+0200", or
+// "+02").  Without a zone, the time is UTC.  The seconds and their
+// fraction are optional.  An empty string or "null" gives the zero
+// time.
+func ParseAnyTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "null" {
+		return time.Time{}, nil
+	}
+	bad := func(err error) (time.Time, error) {
+		return time.Time{}, fmt.Errorf("bad time '%s': %v", s, err)
+	}
+
+	date, clock := s, ""
+	if i := strings.IndexAny(s, "T "); 0 <= i {
+		date, clock = s[:i], strings.TrimSpace(s[i+1:])
+	}
+
+	loc := time.UTC
+	if clock != "" {
+		var err error
+		if clock, loc, err = splitZone(clock); err != nil {
+			return bad(err)
+		}
+	}
+
+	var (
+		year, month, day int
+		err              error
+	)
+	switch ps := strings.Split(date, "-"); {
+	case len(ps) == 3 && len(ps[0]) == 4 && len(ps[1]) == 2 && len(ps[2]) == 2:
+		if year, err = atoiDigits(ps[0]); err != nil {
+			return bad(err)
+		}
+		if month, err = atoiDigits(ps[1]); err != nil {
+			return bad(err)
+		}
+		if day, err = atoiDigits(ps[2]); err != nil {
+			return bad(err)
+		}
+		if month < 1 || 12 < month || day < 1 || daysIn(year, time.Month(month)) < day {
+			return bad(fmt.Errorf("no such date"))
+		}
+	case len(ps) == 2 && len(ps[0]) == 4 && len(ps[1]) == 3:
+		if year, err = atoiDigits(ps[0]); err != nil {
+			return bad(err)
+		}
+		if day, err = atoiDigits(ps[1]); err != nil {
+			return bad(err)
+		}
+		month = 1
+		if day < 1 || time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay() < day {
+			return bad(fmt.Errorf("no such day of the year"))
+		}
+	default:
+		return bad(fmt.Errorf("unknown date form"))
+	}
+
+	var hour, min, sec, ns int
+	if clock != "" {
+		ps := strings.Split(clock, ":")
+		if len(ps) < 2 || 3 < len(ps) || len(ps[0]) != 2 || len(ps[1]) != 2 {
+			return bad(fmt.Errorf("unknown time of day form"))
+		}
+		if hour, err = atoiDigits(ps[0]); err != nil {
+			return bad(err)
+		}
+		if min, err = atoiDigits(ps[1]); err != nil {
+			return bad(err)
+		}
+		if len(ps) == 3 {
+			whole, frac := ps[2], ""
+			if i := strings.IndexByte(whole, '.'); 0 <= i {
+				whole, frac = whole[:i], whole[i+1:]
+				if frac == "" || 9 < len(frac) {
+					return bad(fmt.Errorf("bad fraction of a second"))
+				}
+			}
+			if len(whole) != 2 {
+				return bad(fmt.Errorf("bad seconds"))
+			}
+			if sec, err = atoiDigits(whole); err != nil {
+				return bad(err)
+			}
+			if frac != "" {
+				if ns, err = atoiDigits(frac + strings.Repeat("0", 9-len(frac))); err != nil {
+					return bad(err)
+				}
+			}
+		}
+		// A leap second (:60) isn't representable and just rolls
+		// over.
+		if 23 < hour || 59 < min || 60 < sec {
+			return bad(fmt.Errorf("no such time of day"))
+		}
+	}
+
+	return time.Date(year, time.Month(month), day, hour, min, sec, ns, loc), nil
+}
+
+// splitZone removes a zone suffix from a time of day.
+func splitZone(clock string) (string, *time.Location, error) {
+	switch {
+	case strings.HasSuffix(clock, "Z"), strings.HasSuffix(clock, "z"):
+		return clock[:len(clock)-1], time.UTC, nil
+	case strings.HasSuffix(clock, "UTC"):
+		return strings.TrimSpace(clock[:len(clock)-3]), time.UTC, nil
+	}
+
+	i := strings.LastIndexAny(clock, "+-")
+	if i < 0 {
+		return clock, time.UTC, nil
+	}
+	zone := strings.Replace(clock[i+1:], ":", "", 1)
+	if len(zone) != 2 && len(zone) != 4 {
+		return "", nil, fmt.Errorf("bad zone '%s'", clock[i:])
+	}
+	hours, err := atoiDigits(zone[0:2])
+	if err != nil {
+		return "", nil, err
+	}
+	var mins int
+	if len(zone) == 4 {
+		if mins, err = atoiDigits(zone[2:4]); err != nil {
+			return "", nil, err
+		}
+	}
+	offset := hours*3600 + mins*60
+	if clock[i] == '-' {
+		offset = -offset
+	}
+	loc := time.UTC
+	if offset != 0 {
+		loc = time.FixedZone("", offset)
+	}
+	return strings.TrimSpace(clock[:i]), loc, nil
+}
+
+// atoiDigits parses an unsigned decimal integer.
+func atoiDigits(s string) (int, error) {
+	for _, c := range s {
+		if c < '0' || '9' < c {
+			return 0, fmt.Errorf("'%s' isn't a number", s)
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// ParseTimeExpr parses what ParseAnyTime does as well as "now" and
+// "epoch", either of which can be followed by a signed duration (like
+// "now+6h" or "epoch-90m").  A zero epoch makes "epoch" an error.
+func ParseTimeExpr(s string, now, epoch time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	var base time.Time
+	switch {
+	case strings.HasPrefix(s, "now"):
+		base, s = now, s[len("now"):]
+	case strings.HasPrefix(s, "epoch"):
+		if epoch.IsZero() {
+			return time.Time{}, fmt.Errorf("no epoch for '%s'", s)
+		}
+		base, s = epoch, s[len("epoch"):]
+	default:
+		return ParseAnyTime(s)
+	}
+
+	if s == "" {
+		return base, nil
+	}
+	if s[0] != '+' && s[0] != '-' {
+		return time.Time{}, fmt.Errorf("bad offset '%s'", s)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return base.Add(d), nil
+}
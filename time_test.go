@@ -0,0 +1,139 @@
+package gpelements
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestParseAnyTime(t *testing.T) {
+	var (
+		utc  = time.Date(2020, 12, 13, 3, 44, 10, 913000000, time.UTC)
+		plus = time.Date(2020, 12, 13, 5, 44, 10, 913000000, time.FixedZone("", 2*3600))
+	)
+	for _, tc := range []struct {
+		s    string
+		want time.Time
+		err  bool
+	}{
+		{"2020-12-13T03:44:10.913", utc, false},
+		{"2020-12-13T03:44:10.913Z", utc, false},
+		{"2020-12-13 03:44:10.913 UTC", utc, false},
+		{"2020-348T03:44:10.913", utc, false},
+		{"2020-348T03:44:10.913z", utc, false},
+		{"2020-12-13T05:44:10.913+02:00", plus, false},
+		{"2020-12-13T05:44:10.913+0200", plus, false},
+		{"2020-12-13T05:44:10.913+02", plus, false},
+		{"2020-12-13T01:44:10.913-02:00", utc, false},
+		{"2020-12-13T03:44", time.Date(2020, 12, 13, 3, 44, 0, 0, time.UTC), false},
+		{"2020-12-13", time.Date(2020, 12, 13, 0, 0, 0, 0, time.UTC), false},
+		{"2020-366", time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), false},
+		{"2020-12-13T03:44:10.123456789", time.Date(2020, 12, 13, 3, 44, 10, 123456789, time.UTC), false},
+		{"null", time.Time{}, false},
+		{"", time.Time{}, false},
+		{"2019-366", time.Time{}, true},
+		{"2020-02-30", time.Time{}, true},
+		{"2020-12-13T24:00", time.Time{}, true},
+		{"2020-12-13T03:44:10.", time.Time{}, true},
+		{"2020-12-13T03:44:10.1234567890", time.Time{}, true},
+		{"2020-12-13T03:44+2", time.Time{}, true},
+		{"12/13/2020", time.Time{}, true},
+	} {
+		got, err := ParseAnyTime(tc.s)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("expected an error for '%s'", tc.s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("'%s': %s != %s", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestParseTimeExpr(t *testing.T) {
+	var (
+		now   = time.Date(2020, 12, 13, 0, 0, 0, 0, time.UTC)
+		epoch = time.Date(2020, 12, 10, 0, 0, 0, 0, time.UTC)
+	)
+	for _, tc := range []struct {
+		s    string
+		want time.Time
+	}{
+		{"now", now},
+		{"now+6h", now.Add(6 * time.Hour)},
+		{"now-1h30m", now.Add(-90 * time.Minute)},
+		{"epoch", epoch},
+		{"epoch+90m", epoch.Add(90 * time.Minute)},
+		{"2020-348", now},
+	} {
+		got, err := ParseTimeExpr(tc.s, now, epoch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("'%s': %s != %s", tc.s, got, tc.want)
+		}
+	}
+
+	for _, s := range []string{"now6h", "now+6", "later"} {
+		if _, err := ParseTimeExpr(s, now, epoch); err == nil {
+			t.Fatalf("expected an error for '%s'", s)
+		}
+	}
+	if _, err := ParseTimeExpr("epoch", now, time.Time{}); err == nil {
+		t.Fatal("expected an error without an epoch")
+	}
+}
+
+func TestTimeUnmarshal(t *testing.T) {
+	want := time.Date(2020, 12, 13, 3, 44, 10, 900000000, time.UTC)
+
+	var x Time
+	if err := json.Unmarshal([]byte(`"2020-348T03:44:10.9Z"`), &x); err != nil {
+		t.Fatal(err)
+	}
+	if !time.Time(x).Equal(want) {
+		t.Fatal(time.Time(x))
+	}
+
+	var y struct {
+		T Time `xml:"T"`
+	}
+	if err := xml.Unmarshal([]byte(`<x><T>2020-12-13T03:44:10.9Z</T></x>`), &y); err != nil {
+		t.Fatal(err)
+	}
+	if !time.Time(y.T).Equal(want) {
+		t.Fatal(time.Time(y.T))
+	}
+
+	// MarshalJSON writes "null" for a nil Time.
+	var (
+		nothing *Time
+		z       Time
+	)
+	js, err := json.Marshal(nothing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(js, &z); err != nil {
+		t.Fatal(err)
+	}
+	if !time.Time(z).IsZero() {
+		t.Fatal(time.Time(z))
+	}
+
+	// An OMM time field that's "null" is nil.
+	e := NewElements()
+	if err = json.Unmarshal([]byte(`{"EPOCH":"2020-12-13T03:44:10.9Z","CREATION_DATE":"null"}`), e); err != nil {
+		t.Fatal(err)
+	}
+	if e.CreationDate != nil || !time.Time(*e.Epoch).Equal(want) {
+		t.Fatal(e.CreationDate, e.Epoch)
+	}
+}
@@ -0,0 +1,362 @@
+package gpelements
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeScale is a time scale as named by an OMM's TIME_SYSTEM.
+//
+// A time.Time in a scale other than UTC is represented by its clock
+// reading in that scale (in the time.UTC location).  For example, the
+// TT time.Time for an instant is 69.184 s after the UTC time.Time (as
+// of 2017).
+type TimeScale string
+
+const (
+	UTC TimeScale = "UTC"
+
+	// TAI is International Atomic Time.
+	TAI TimeScale = "TAI"
+
+	// TT is Terrestrial Time, which is TAI + 32.184 s.
+	TT TimeScale = "TT"
+
+	// GPS is GPS time, which is TAI - 19 s.
+	GPS TimeScale = "GPS"
+
+	// UT1 follows the Earth's rotation.  It's UTC + UT1MinusUTC.
+	UT1 TimeScale = "UT1"
+)
+
+// ParseTimeScale parses a TIME_SYSTEM.  An empty string is UTC.
+func ParseTimeScale(s string) (TimeScale, error) {
+	switch x := TimeScale(strings.ToUpper(strings.TrimSpace(s))); x {
+	case "":
+		return UTC, nil
+	case UTC, TAI, TT, GPS, UT1:
+		return x, nil
+	}
+	return "", fmt.Errorf("unsupported time system '%s'", s)
+}
+
+const (
+	ttMinusTAI  = 32184 * time.Millisecond
+	taiMinusGPS = 19 * time.Second
+)
+
+// FromUTC returns the reading in this scale for the UTC time.
+func (s TimeScale) FromUTC(t time.Time) (time.Time, error) {
+	t = t.UTC()
+	switch s {
+	case UTC:
+		return t, nil
+	case TAI:
+		return t.Add(TAIMinusUTC(t)), nil
+	case TT:
+		return t.Add(TAIMinusUTC(t) + ttMinusTAI), nil
+	case GPS:
+		return t.Add(TAIMinusUTC(t) - taiMinusGPS), nil
+	case UT1:
+		return t.Add(UT1MinusUTC(t)), nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported time scale '%s'", s)
+}
+
+// ToUTC returns the UTC time for the reading in this scale.
+func (s TimeScale) ToUTC(t time.Time) (time.Time, error) {
+	t = t.UTC()
+	var d time.Duration
+	switch s {
+	case UTC:
+		return t, nil
+	case TAI:
+	case TT:
+		d = ttMinusTAI
+	case GPS:
+		d = -taiMinusGPS
+	case UT1:
+		// UT1 - UTC changes slowly.
+		return t.Add(-UT1MinusUTC(t.Add(-UT1MinusUTC(t)))), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported time scale '%s'", s)
+	}
+	tai := t.Add(-d)
+	return tai.Add(-TAIMinusUTC(tai.Add(-TAIMinusUTC(tai)))), nil
+}
+
+// LeapSecond is a change in TAI - UTC.
+type LeapSecond struct {
+	// At is the UTC time that the change takes effect.
+	At time.Time
+
+	// TAIMinusUTC is TAI - UTC in seconds from At on.
+	TAIMinusUTC int
+}
+
+func leap(year int, month time.Month, taiMinusUTC int) LeapSecond {
+	return LeapSecond{
+		At:          time.Date(year, month, 1, 0, 0, 0, 0, time.UTC),
+		TAIMinusUTC: taiMinusUTC,
+	}
+}
+
+// LeapSeconds is the leap second table in chronological order.
+//
+// Replace it (perhaps with ParseLeapSeconds) when the IERS announces a
+// new leap second.
+var LeapSeconds = []LeapSecond{
+	leap(1972, time.January, 10),
+	leap(1972, time.July, 11),
+	leap(1973, time.January, 12),
+	leap(1974, time.January, 13),
+	leap(1975, time.January, 14),
+	leap(1976, time.January, 15),
+	leap(1977, time.January, 16),
+	leap(1978, time.January, 17),
+	leap(1979, time.January, 18),
+	leap(1980, time.January, 19),
+	leap(1981, time.July, 20),
+	leap(1982, time.July, 21),
+	leap(1983, time.July, 22),
+	leap(1985, time.July, 23),
+	leap(1988, time.January, 24),
+	leap(1990, time.January, 25),
+	leap(1991, time.January, 26),
+	leap(1992, time.July, 27),
+	leap(1993, time.July, 28),
+	leap(1994, time.July, 29),
+	leap(1996, time.January, 30),
+	leap(1997, time.July, 31),
+	leap(1999, time.January, 32),
+	leap(2006, time.January, 33),
+	leap(2009, time.January, 34),
+	leap(2012, time.July, 35),
+	leap(2015, time.July, 36),
+	leap(2017, time.January, 37),
+}
+
+// TAIMinusUTC returns TAI - UTC at the UTC time according to
+// LeapSeconds.  Before 1972, when the difference wasn't a whole
+// number of seconds, it's just the first entry's.
+func TAIMinusUTC(t time.Time) time.Duration {
+	ls := LeapSeconds
+	if len(ls) == 0 {
+		return 0
+	}
+	i := sort.Search(len(ls), func(i int) bool {
+		return t.Before(ls[i].At)
+	})
+	if i == 0 {
+		i = 1
+	}
+	return time.Duration(ls[i-1].TAIMinusUTC) * time.Second
+}
+
+// ntpEpoch is the zero of NTP timestamps.
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ParseLeapSeconds reads a leap second table in the format of the
+// IERS's leap-seconds.list, where each line (other than comments
+// starting with '#') has the NTP timestamp (seconds since 1900) of a
+// change and the new TAI - UTC.
+func ParseLeapSeconds(r io.Reader) ([]LeapSecond, error) {
+	var (
+		in   = bufio.NewScanner(r)
+		line = 0
+		acc  = make([]LeapSecond, 0, 32)
+	)
+	for in.Scan() {
+		line++
+		s := in.Text()
+		if i := strings.IndexByte(s, '#'); 0 <= i {
+			s = s[:i]
+		}
+		fs := strings.Fields(s)
+		if len(fs) == 0 {
+			continue
+		}
+		if len(fs) < 2 {
+			return nil, fmt.Errorf("leap seconds line %d: expected two numbers", line)
+		}
+		secs, err := strconv.ParseInt(fs[0], 10, 64)
+		if err != nil {
+			return nil, wrapErrf(err, "leap seconds line %d", line)
+		}
+		n, err := strconv.Atoi(fs[1])
+		if err != nil {
+			return nil, wrapErrf(err, "leap seconds line %d", line)
+		}
+		acc = append(acc, LeapSecond{
+			At:          ntpEpoch.Add(time.Duration(secs) * time.Second),
+			TAIMinusUTC: n,
+		})
+	}
+	if err := in.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(acc, func(i, j int) bool {
+		return acc[i].At.Before(acc[j].At)
+	})
+	return acc, nil
+}
+
+// EOPRecord is a day's Earth orientation parameters.
+type EOPRecord struct {
+	// MJD is the UTC modified Julian date.
+	MJD float64
+
+	// UT1MinusUTC is in seconds.
+	UT1MinusUTC float64
+
+	// PolarX and PolarY are the coordinates of the pole in
+	// arcseconds.
+	PolarX, PolarY float64
+}
+
+// EOP is a series of Earth orientation parameters.
+type EOP struct {
+	Records []EOPRecord
+}
+
+// DefaultEOP, if not nil, provides UT1MinusUTC and polar motion.
+// Otherwise UT1 is UTC, and the pole doesn't move.
+var DefaultEOP *EOP
+
+// ReadEOP reads Earth orientation parameters in the IERS's "finals"
+// format (like finals2000A.all).  Lines without a UT1 - UTC value are
+// skipped.
+func ReadEOP(r io.Reader) (*EOP, error) {
+	var (
+		in   = bufio.NewScanner(r)
+		line = 0
+		eop  = &EOP{}
+	)
+	for in.Scan() {
+		line++
+		s := in.Text()
+		if len(s) < 68 || strings.TrimSpace(s[58:68]) == "" {
+			continue
+		}
+		var (
+			rec EOPRecord
+			err error
+		)
+		for _, f := range []struct {
+			from, to int
+			x        *float64
+		}{
+			{7, 15, &rec.MJD},
+			{18, 27, &rec.PolarX},
+			{37, 46, &rec.PolarY},
+			{58, 68, &rec.UT1MinusUTC},
+		} {
+			if *f.x, err = strconv.ParseFloat(strings.TrimSpace(s[f.from:f.to]), 64); err != nil {
+				return nil, wrapErrf(err, "EOP line %d columns %d-%d", line, f.from+1, f.to)
+			}
+		}
+		eop.Records = append(eop.Records, rec)
+	}
+	if err := in.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(eop.Records, func(i, j int) bool {
+		return eop.Records[i].MJD < eop.Records[j].MJD
+	})
+	return eop, nil
+}
+
+// LoadEOP reads Earth orientation parameters from the named file.
+func LoadEOP(filename string) (*EOP, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close() // Ignore error.
+	return ReadEOP(in)
+}
+
+// mjd returns the modified Julian date of the time.
+func mjd(t time.Time) float64 {
+	return float64(t.UnixNano())/1e9/86400 + 40587
+}
+
+// At returns the parameters interpolated for the UTC time.  Outside
+// the series, the nearest record is used.  An empty series gives
+// zeros.
+//
+// UT1 - UTC jumps at leap seconds, so it's interpolated as UT1 - TAI.
+func (eop *EOP) At(t time.Time) EOPRecord {
+	if eop == nil || len(eop.Records) == 0 {
+		return EOPRecord{}
+	}
+	var (
+		rs = eop.Records
+		m  = mjd(t)
+		i  = sort.Search(len(rs), func(i int) bool {
+			return m < rs[i].MJD
+		})
+	)
+	switch {
+	case i == 0:
+		return rs[0]
+	case i == len(rs):
+		return rs[len(rs)-1]
+	}
+
+	var (
+		a, b = rs[i-1], rs[i]
+		f    = (m - a.MJD) / (b.MJD - a.MJD)
+		lerp = func(x, y float64) float64 {
+			return x + f*(y-x)
+		}
+		dat = func(mjd float64) float64 {
+			return TAIMinusUTC(time.Unix(int64((mjd-40587)*86400), 0).UTC()).Seconds()
+		}
+		ut1tai = lerp(a.UT1MinusUTC-dat(a.MJD), b.UT1MinusUTC-dat(b.MJD))
+	)
+	return EOPRecord{
+		MJD:         m,
+		UT1MinusUTC: ut1tai + TAIMinusUTC(t).Seconds(),
+		PolarX:      lerp(a.PolarX, b.PolarX),
+		PolarY:      lerp(a.PolarY, b.PolarY),
+	}
+}
+
+// UT1MinusUTC returns UT1 - UTC at the UTC time according to
+// DefaultEOP.
+func UT1MinusUTC(t time.Time) time.Duration {
+	s := DefaultEOP.At(t).UT1MinusUTC
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+// UseUTC converts the Epoch and RefFrameEpoch from the element set's
+// TIME_SYSTEM to UTC, which then becomes the TIME_SYSTEM.
+func (e *Elements) UseUTC() error {
+	scale, err := ParseTimeScale(e.TimeSystem)
+	if err != nil {
+		return err
+	}
+	if scale == UTC {
+		return nil
+	}
+	for _, p := range []**Time{&e.Epoch, &e.RefFrameEpoch} {
+		if *p == nil {
+			continue
+		}
+		t, err := scale.ToUTC(time.Time(**p))
+		if err != nil {
+			return err
+		}
+		*p = NewTime(t)
+	}
+	e.TimeSystem = string(UTC)
+	return nil
+}
@@ -0,0 +1,183 @@
+package gpelements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTAIMinusUTC(t *testing.T) {
+	for _, tc := range []struct {
+		t    time.Time
+		secs int
+	}{
+		{time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), 10},
+		{time.Date(1972, 6, 30, 23, 59, 59, 0, time.UTC), 10},
+		{time.Date(1972, 7, 1, 0, 0, 0, 0, time.UTC), 11},
+		{time.Date(2016, 12, 31, 23, 59, 59, 0, time.UTC), 36},
+		{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), 37},
+		{time.Date(2020, 12, 13, 0, 0, 0, 0, time.UTC), 37},
+	} {
+		if got, want := TAIMinusUTC(tc.t), time.Duration(tc.secs)*time.Second; got != want {
+			t.Fatalf("%s: %s != %s", tc.t, got, want)
+		}
+	}
+}
+
+func TestTimeScales(t *testing.T) {
+	utc := time.Date(2020, 12, 13, 3, 44, 10, 0, time.UTC)
+	for _, tc := range []struct {
+		scale TimeScale
+		d     time.Duration
+	}{
+		{UTC, 0},
+		{TAI, 37 * time.Second},
+		{TT, 69184 * time.Millisecond},
+		{GPS, 18 * time.Second},
+		{UT1, 0}, // Without DefaultEOP.
+	} {
+		x, err := tc.scale.FromUTC(utc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := x.Sub(utc); got != tc.d {
+			t.Fatalf("%s: %s != %s", tc.scale, got, tc.d)
+		}
+		back, err := tc.scale.ToUTC(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !back.Equal(utc) {
+			t.Fatalf("%s: %s != %s", tc.scale, back, utc)
+		}
+	}
+
+	// Just after a leap second.
+	utc = time.Date(2017, 1, 1, 0, 0, 1, 0, time.UTC)
+	tai, _ := TAI.FromUTC(utc)
+	if back, _ := TAI.ToUTC(tai); !back.Equal(utc) {
+		t.Fatalf("%s != %s", back, utc)
+	}
+
+	if _, err := ParseTimeScale("TDB"); err == nil {
+		t.Fatal("expected an error for TDB")
+	}
+	if s, err := ParseTimeScale("tt"); err != nil || s != TT {
+		t.Fatal(s, err)
+	}
+}
+
+func TestParseLeapSeconds(t *testing.T) {
+	in := `# Comment
+#$	 3676924800
+2272060800	10	# 1 Jan 1972
+2287785600	11	# 1 Jul 1972
+
+3692217600	37	# 1 Jan 2017
+`
+	ls, err := ParseLeapSeconds(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ls) != 3 {
+		t.Fatal(ls)
+	}
+	if want := LeapSeconds[len(LeapSeconds)-1]; !ls[2].At.Equal(want.At) || ls[2].TAIMinusUTC != want.TAIMinusUTC {
+		t.Fatal(ls[2])
+	}
+	if !ls[1].At.Equal(LeapSeconds[1].At) {
+		t.Fatal(ls[1])
+	}
+}
+
+// finalsLine formats a line in the IERS finals format.
+func finalsLine(date string, mjd, pmx, pmy, dut1 float64) string {
+	return fmt.Sprintf("%6s %8.2f I %9.6f%9.6f %9.6f%9.6f  I%10.7f%10.7f",
+		date, mjd, pmx, 0.0, pmy, 0.0, dut1, 0.0)
+}
+
+func TestEOP(t *testing.T) {
+	in := strings.Join([]string{
+		finalsLine("161230", 57752, 0.0, 0.28, -0.4066),
+		finalsLine("161231", 57753, 0.01, 0.29, -0.4071),
+		finalsLine("17 1 1", 57754, 0.02, 0.30, 0.5923),
+		"17 1 2 57755.00 P  0.030000 0.000000  0.310000 0.000000",
+	}, "\n")
+
+	eop, err := ReadEOP(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eop.Records) != 3 {
+		t.Fatal(eop.Records)
+	}
+
+	near := func(x, y float64) bool {
+		return math.Abs(x-y) < 1e-9
+	}
+
+	// Midday before the leap second.
+	r := eop.At(time.Date(2016, 12, 31, 12, 0, 0, 0, time.UTC))
+	if !near(r.UT1MinusUTC, -0.4074) || !near(r.PolarX, 0.015) || !near(r.PolarY, 0.295) {
+		t.Fatalf("%#v", r)
+	}
+
+	// Just after.
+	r = eop.At(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !near(r.UT1MinusUTC, 0.5923) {
+		t.Fatalf("%#v", r)
+	}
+
+	// Beyond the series.
+	r = eop.At(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !near(r.UT1MinusUTC, 0.5923) {
+		t.Fatalf("%#v", r)
+	}
+
+	defer func(was *EOP) {
+		DefaultEOP = was
+	}(DefaultEOP)
+
+	at := time.Date(2016, 12, 31, 0, 0, 0, 0, time.UTC)
+	gst0, _ := TimeToGST(at)
+	DefaultEOP = eop
+	gst1, _ := TimeToGST(at)
+
+	// The Earth turns about 7.29e-5 rad/s.
+	if d := (gst1 - gst0) / 7.2921159e-5; math.Abs(d-(-0.4071)) > 1e-3 {
+		t.Fatal(d)
+	}
+
+	ut1, err := UT1.FromUTC(at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := ut1.Sub(at).Seconds(); !near(d, -0.4071) {
+		t.Fatal(d)
+	}
+}
+
+func TestUseUTC(t *testing.T) {
+	e := NewElements()
+	js := `{"EPOCH":"2020-12-13T00:01:09.184","TIME_SYSTEM":"TT"}`
+	if err := json.Unmarshal([]byte(js), e); err != nil {
+		t.Fatal(err)
+	}
+	if e.TimeSystem != "UTC" {
+		t.Fatal(e.TimeSystem)
+	}
+	if want := time.Date(2020, 12, 13, 0, 0, 0, 0, time.UTC); !time.Time(*e.Epoch).Equal(want) {
+		t.Fatal(e.Epoch)
+	}
+
+	js = `{"EPOCH":"2020-12-13T00:01:09.184","TIME_SYSTEM":"TDB"}`
+	err := json.Unmarshal([]byte(js), NewElements())
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Field != "TIME_SYSTEM" {
+		t.Fatalf("%#v", err)
+	}
+}
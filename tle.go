@@ -4,18 +4,39 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// tleLineLen is the length of a TLE data line including the
+// checksum.
+const tleLineLen = 69
+
+// ParseTLE is ParseTLEWith using DefaultParseOptions.
 func ParseTLE(line0, line1, line2 string) (*Elements, error) {
+	return ParseTLEWith(line0, line1, line2, DefaultParseOptions)
+}
+
+// ParseTLEWith parses a TLE.  The name line (line0) can be empty.
+//
+// Problems that opts tolerates are reported in the returned Elements'
+// Warnings.
+func ParseTLEWith(line0, line1, line2 string, opts ParseOptions) (*Elements, error) {
 	var (
 		e   = NewElements()
 		err error
 		s   string
 	)
 
+	if line1, err = opts.checkTLELine(e, line1, 1); err != nil {
+		return nil, err
+	}
+	if line2, err = opts.checkTLELine(e, line2, 2); err != nil {
+		return nil, err
+	}
+
 	if strings.HasPrefix(line0, "0 ") {
 		e.Name = strings.TrimRight(line0[2:], " ")
 	} else {
@@ -23,46 +44,50 @@ func ParseTLE(line0, line1, line2 string) (*Elements, error) {
 	}
 
 	if s, err = tleExtract(line1, 3, 7); err != nil {
-		return nil, wrapErrf(err, "NoradCatId")
+		return nil, tleError(line1, 1, 3, 7, "NORAD_CAT_ID", err)
 	}
 	e.NoradCatId = NewNoradCatId(s).Decode()
 
 	if s, err = tleExtract(line1, 8, 8); err != nil {
-		return nil, wrapErrf(err, "ClassificationType")
+		return nil, tleError(line1, 1, 8, 8, "CLASSIFICATION_TYPE", err)
 	}
 	e.ClassificationType = s
 
 	{
 		if s, err = tleExtract(line1, 10, 11); err != nil {
-			return nil, wrapErrf(err, "LaunchYear: '%s'", line1)
+			return nil, tleError(line1, 1, 10, 11, "OBJECT_ID", err)
 		}
 		var year int
 		if year, err = strconv.Atoi(s); err != nil {
-			// return nil, wrapErrf(err, "LaunchYear: '%s'", line1)
-			// For now (!), silently igore.
-		} else {
-			if year < 56 {
-				year = 2000 + year
-			} else {
-				year = 1900 + year
+			if strings.TrimSpace(s) != "" {
+				err = tleError(line1, 1, 10, 11, "OBJECT_ID", wrapErrf(err, "malformed international designator year"))
+				if err = opts.problem(e, true, err); err != nil {
+					return nil, err
+				}
 			}
+		} else {
+			year = opts.Years.Expand(year)
 		}
 		e.LaunchYear = year
 	}
 
 	if s, err = tleExtract(line1, 12, 14); err != nil {
-		return nil, wrapErrf(err, "LaunchNum")
+		return nil, tleError(line1, 1, 12, 14, "OBJECT_ID", err)
 	}
 	s = strings.TrimLeft(s, "0")
 	if 0 < len(s) {
 		if e.LaunchNum, err = strconv.Atoi(s); err != nil {
-			// return nil, wrapErrf(err, "LaunchNum: %s", line1)
-			// For now (!), silently igore.
+			if strings.TrimSpace(s) != "" {
+				err = tleError(line1, 1, 12, 14, "OBJECT_ID", wrapErrf(err, "malformed international designator launch number"))
+				if err = opts.problem(e, true, err); err != nil {
+					return nil, err
+				}
+			}
 		}
 	}
 
 	if s, err = tleExtract(line1, 15, 17); err != nil {
-		return nil, wrapErrf(err, "LaunchPiece")
+		return nil, tleError(line1, 1, 15, 17, "OBJECT_ID", err)
 	}
 	s = strings.TrimRight(s, " ")
 	e.LaunchPiece = s
@@ -73,42 +98,37 @@ func ParseTLE(line0, line1, line2 string) (*Elements, error) {
 
 		var year int
 		if s, err = tleExtract(line1, 19, 20); err != nil {
-			return nil, wrapErrf(err, "Epoch year")
+			return nil, tleError(line1, 1, 19, 20, "EPOCH", err)
 		}
 		if year, err = strconv.Atoi(s); err != nil {
-			return nil, wrapErrf(err, "Epoch year")
-		}
-		if year < 56 {
-			year = 2000 + year
-		} else {
-			year = 1900 + year
+			return nil, tleError(line1, 1, 19, 20, "EPOCH", err)
 		}
+		year = opts.Years.Expand(year)
 
 		loc := time.FixedZone("UTC", 0)
 		t := time.Date(year, 1, 0, 0, 0, 0, 0, loc)
 
-		var day float64
+		var d time.Duration
 		if s, err = tleExtract(line1, 21, 32); err != nil {
-			return nil, wrapErrf(err, "Epoch year")
+			return nil, tleError(line1, 1, 21, 32, "EPOCH", err)
 		}
-		if day, err = strconv.ParseFloat(s, 64); err != nil {
-			return nil, wrapErrf(err, "Epoch day")
+		if d, err = parseTLEDay(s); err != nil {
+			return nil, tleError(line1, 1, 21, 32, "EPOCH", err)
 		}
-		hours := day * 24
-		t0 := Time(t.Add(time.Duration(hours * float64(time.Hour))))
+		t0 := Time(t.Add(d))
 		e.Epoch = &t0
 	}
 
 	if s, err = tleExtract(line1, 34, 43); err != nil {
-		return nil, wrapErrf(err, "MeanMotionDot")
+		return nil, tleError(line1, 1, 34, 43, "MEAN_MOTION_DOT", err)
 	}
 	s = strings.TrimLeft(s, " ")
 	if e.MeanMotionDot, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "MeanMotionDot '%s'", s)
+		return nil, tleError(line1, 1, 34, 43, "MEAN_MOTION_DOT", err)
 	}
 
 	if s, err = tleExtract(line1, 45, 52); err != nil {
-		return nil, wrapErrf(err, "MeanMotionDDot")
+		return nil, tleError(line1, 1, 45, 52, "MEAN_MOTION_DDOT", err)
 	}
 	switch s[0] {
 	case ' ':
@@ -118,11 +138,11 @@ func ParseTLE(line0, line1, line2 string) (*Elements, error) {
 		s = "-0." + s[1:]
 	}
 	if e.MeanMotionDDot, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "MeanMotionDDot '%s'", s)
+		return nil, tleError(line1, 1, 45, 52, "MEAN_MOTION_DDOT", err)
 	}
 
 	if s, err = tleExtract(line1, 54, 61); err != nil {
-		return nil, wrapErrf(err, "BStar")
+		return nil, tleError(line1, 1, 54, 61, "BSTAR", err)
 	}
 	switch s[0] {
 	case ' ':
@@ -132,46 +152,45 @@ func ParseTLE(line0, line1, line2 string) (*Elements, error) {
 		s = "-0." + s[1:]
 	}
 	if e.BStar, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "BStar '%s'", s)
+		return nil, tleError(line1, 1, 54, 61, "BSTAR", err)
 	}
 
 	e.EphemerisType = 0
 
 	if s, err = tleExtract(line1, 65, 68); err != nil {
-		return nil, wrapErrf(err, "ElementSet")
+		return nil, tleError(line1, 1, 65, 68, "ELEMENT_SET_NO", err)
 	}
 	s = strings.TrimLeft(s, " ")
 	if e.ElementSet, err = strconv.Atoi(s); err != nil {
-		return nil, wrapErrf(err, "ElementSet")
+		return nil, tleError(line1, 1, 65, 68, "ELEMENT_SET_NO", err)
 	}
 
-	// Skip checksum?
-
 	if s, err = tleExtract(line2, 3, 7); err != nil {
-		return nil, wrapErrf(err, "NoradCatId")
+		return nil, tleError(line2, 2, 3, 7, "NORAD_CAT_ID", err)
 	}
 	if id := NewNoradCatId(s).Decode(); id != e.NoradCatId {
-		return nil, fmt.Errorf("NoradCatId disagreement: '%s' != '%s'", string(id), string(e.NoradCatId))
+		err = fmt.Errorf("disagreement: '%s' != '%s'", string(id), string(e.NoradCatId))
+		return nil, tleError(line2, 2, 3, 7, "NORAD_CAT_ID", err)
 	}
 
 	if s, err = tleExtract(line2, 9, 16); err != nil {
-		return nil, wrapErrf(err, "Inclination")
+		return nil, tleError(line2, 2, 9, 16, "INCLINATION", err)
 	}
 	s = strings.TrimLeft(s, " ")
 	if e.Inclination, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "Inclination '%s'", s)
+		return nil, tleError(line2, 2, 9, 16, "INCLINATION", err)
 	}
 
 	if s, err = tleExtract(line2, 18, 25); err != nil {
-		return nil, wrapErrf(err, "RightAscention")
+		return nil, tleError(line2, 2, 18, 25, "RA_OF_ASC_NODE", err)
 	}
 	s = strings.TrimLeft(s, " ")
 	if e.RightAscension, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "RightAscention '%s'", s)
+		return nil, tleError(line2, 2, 18, 25, "RA_OF_ASC_NODE", err)
 	}
 
 	if s, err = tleExtract(line2, 27, 33); err != nil {
-		return nil, wrapErrf(err, "Eccentricity")
+		return nil, tleError(line2, 2, 27, 33, "ECCENTRICITY", err)
 	}
 	switch s[0] {
 	case ' ':
@@ -183,44 +202,100 @@ func ParseTLE(line0, line1, line2 string) (*Elements, error) {
 		s = "0." + s
 	}
 	if e.Eccentricity, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "Eccentricity '%s'", s)
+		return nil, tleError(line2, 2, 27, 33, "ECCENTRICITY", err)
 	}
 
 	if s, err = tleExtract(line2, 35, 42); err != nil {
-		return nil, wrapErrf(err, "ArgOfPericenter")
+		return nil, tleError(line2, 2, 35, 42, "ARG_OF_PERICENTER", err)
 	}
 	s = strings.TrimLeft(s, " ")
 	if e.ArgOfPericenter, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "ArgOfPericenter '%s'", s)
+		return nil, tleError(line2, 2, 35, 42, "ARG_OF_PERICENTER", err)
 	}
 
 	if s, err = tleExtract(line2, 44, 51); err != nil {
-		return nil, wrapErrf(err, "MeanAnomaly")
+		return nil, tleError(line2, 2, 44, 51, "MEAN_ANOMALY", err)
 	}
 	s = strings.TrimLeft(s, " ")
 	if e.MeanAnomaly, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "MeanAnomaly '%s'", s)
+		return nil, tleError(line2, 2, 44, 51, "MEAN_ANOMALY", err)
 	}
 
 	if s, err = tleExtract(line2, 53, 63); err != nil {
-		return nil, wrapErrf(err, "MeanMotion")
+		return nil, tleError(line2, 2, 53, 63, "MEAN_MOTION", err)
 	}
 	s = strings.TrimLeft(s, " ")
 	if e.MeanMotion, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "MeanMotion '%s'", s)
+		return nil, tleError(line2, 2, 53, 63, "MEAN_MOTION", err)
 	}
 
 	if s, err = tleExtract(line2, 64, 68); err != nil {
-		return nil, wrapErrf(err, "RevAtEpoch '%s'", s)
+		return nil, tleError(line2, 2, 64, 68, "REV_AT_EPOCH", err)
 	}
 	s = strings.TrimLeft(s, " ")
 	if e.RevAtEpoch, err = tleParseFloat(s); err != nil {
-		return nil, wrapErrf(err, "RevAtEpoch '%s'", s)
+		return nil, tleError(line2, 2, 64, 68, "REV_AT_EPOCH", err)
 	}
 
 	return e, nil
 }
 
+// checkTLELine pads a short line (unless strict) and verifies its
+// checksum (as the options say).
+func (o ParseOptions) checkTLELine(e *Elements, line string, which int) (string, error) {
+	line = strings.TrimRight(line, " ")
+
+	if len(line) < tleLineLen {
+		err := &ParseError{
+			Format: "tle",
+			Line:   which,
+			Text:   line,
+			Err:    fmt.Errorf("%d characters, not %d", len(line), tleLineLen),
+		}
+		if err := o.problem(e, false, err); err != nil {
+			return "", err
+		}
+		line += strings.Repeat(" ", tleLineLen-len(line))
+	}
+
+	var (
+		given = line[tleLineLen-1 : tleLineLen]
+		want  = checksum(line[0 : tleLineLen-1])
+	)
+	switch given {
+	case want:
+	case " ":
+		err := tleError(line, which, tleLineLen, tleLineLen, "", fmt.Errorf("no checksum"))
+		if err := o.problem(e, o.Strictness != Lenient, err); err != nil {
+			return "", err
+		}
+	default:
+		err := tleError(line, which, tleLineLen, tleLineLen, "", fmt.Errorf("checksum %s != %s", given, want))
+		if err := o.problem(e, o.Strictness != Lenient, err); err != nil {
+			return "", err
+		}
+	}
+
+	return line, nil
+}
+
+// tleError makes a ParseError for the given columns of line 1 or 2
+// of a TLE.
+func tleError(line string, which, col0, col1 int, field string, err error) *ParseError {
+	text := line
+	if s, err := tleExtract(line, col0, col1); err == nil {
+		text = s
+	}
+	return &ParseError{
+		Format:  "tle",
+		Line:    which,
+		Field:   field,
+		Columns: [2]int{col0, col1},
+		Text:    text,
+		Err:     err,
+	}
+}
+
 func wrapErrf(err error, format string, args ...interface{}) error {
 	msg := fmt.Sprintf(format, args...)
 	return fmt.Errorf("%s: %s", msg, err)
@@ -233,6 +308,67 @@ func tleParseFloat(s string) (float64, error) {
 	return strconv.ParseFloat(s0+s, 64)
 }
 
+// tleDayUnit is the resolution of a TLE epoch: 1e-8 of a day.
+const tleDayUnit = 24 * time.Hour / 1e8
+
+// parseTLEDay parses a TLE epoch day (like "264.51782528") exactly.
+func parseTLEDay(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var (
+		whole = s
+		frac  string
+	)
+	if i := strings.IndexByte(s, '.'); 0 <= i {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("bad day '%s'", s)
+	}
+	for _, c := range whole + frac {
+		if c < '0' || '9' < c {
+			return 0, fmt.Errorf("bad day '%s'", s)
+		}
+	}
+
+	var days int64
+	if whole != "" {
+		n, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil || 366 < n {
+			return 0, fmt.Errorf("bad day '%s'", s)
+		}
+		days = n
+	}
+
+	// The fraction in units of 1e-11 day, which is 864 ns.  The
+	// field only has room for 10 digits anyway.
+	if 11 < len(frac) {
+		return 0, fmt.Errorf("bad day '%s'", s)
+	}
+	var f int64
+	for i := 0; i < 11; i++ {
+		f *= 10
+		if i < len(frac) {
+			f += int64(frac[i] - '0')
+		}
+	}
+	return time.Duration(days)*24*time.Hour + time.Duration(f*864), nil
+}
+
+// formatTLEDay formats the time since day 0 of the year as a TLE
+// epoch day (like "264.51782528"), rounding to the nearest
+// tleDayUnit.
+func formatTLEDay(d time.Duration) string {
+	var (
+		days = d / (24 * time.Hour)
+		frac = (d%(24*time.Hour) + tleDayUnit/2) / tleDayUnit
+	)
+	if frac == 1e8 {
+		days++
+		frac = 0
+	}
+	return fmt.Sprintf("%03d.%08d", days, frac)
+}
+
 func tleExtract(line string, col0, col1 int) (string, error) {
 	var (
 		from = col0 - 1
@@ -245,38 +381,52 @@ func tleExtract(line string, col0, col1 int) (string, error) {
 	return line[from:to], nil
 }
 
-func year56(year int) int {
-	if year < 1955 {
-		return 0
-	}
-	if year < 2000 {
-		return year - 1900
-	}
-	return year - 2000
+// MarshalTLE is MarshalTLEWith using DefaultMarshalOptions.
+func (e *Elements) MarshalTLE() (line0, line1, line2 string, err error) {
+	return e.MarshalTLEWith(DefaultMarshalOptions)
 }
 
-func (e *Elements) MarshalTLE() (line0, line1, line2 string, err error) {
+// MarshalTLEWith renders the element set as a TLE.
+//
+// Catalog numbers from 100000 to MaxAlpha5 use Alpha-5.  Larger
+// numbers are an error unless opts allow Base62.
+func (e *Elements) MarshalTLEWith(opts MarshalOptions) (line0, line1, line2 string, err error) {
 	line0 = fmt.Sprintf("0 % -22s", e.Name)
 
+	catNum, err := e.NoradCatId.tle(opts.Base62)
+	if err != nil {
+		return "", "", "", err
+	}
+
 	// Slowly (very) and clearly (hopefully).
 	s := "1 "
 
-	s += fmt.Sprintf("% -5s%s ", e.NoradCatId.Encode(), e.ClassificationType)
+	s += fmt.Sprintf("% -5s%s ", catNum, e.ClassificationType)
 
 	if err := e.UseInternationalDesignator(); err != nil {
 		return "", "", "", err
 	}
-	s += fmt.Sprintf("%02d%03d%-3s ", year56(e.LaunchYear), e.LaunchNum, e.LaunchPiece)
+	// A zero LaunchYear means there's no international designator.
+	var launchYear int
+	if e.LaunchYear != 0 {
+		if launchYear, err = opts.Years.TwoDigits(e.LaunchYear); err != nil {
+			return "", "", "", wrapErrf(err, "launch year")
+		}
+	}
+	s += fmt.Sprintf("%02d%03d%-3s ", launchYear, e.LaunchNum, e.LaunchPiece)
 
 	var (
-		year = time.Time(*e.Epoch).Year()
-		loc  = time.FixedZone("UTC", 0)
-		t0   = time.Date(year, 1, 0, 0, 0, 0, 0, loc) // day == 0 is strange.
-		d    = time.Time(*e.Epoch).Sub(t0)
-		day  = float64(d.Nanoseconds()) / 1000 / 1000 / 1000 / 60 / 60 / 24
+		// Rounding first might carry the epoch into the next year.
+		epoch = time.Time(*e.Epoch).UTC().Round(tleDayUnit)
+		year  = epoch.Year()
+		t0    = time.Date(year, 1, 0, 0, 0, 0, 0, time.UTC) // day == 0 is strange.
 	)
 
-	s += fmt.Sprintf("%02d%012.8f ", year56(year), day)
+	yy, err := opts.Years.TwoDigits(year)
+	if err != nil {
+		return "", "", "", wrapErrf(err, "epoch")
+	}
+	s += fmt.Sprintf("%02d%s ", yy, formatTLEDay(epoch.Sub(t0)))
 
 	{ // 9
 		x := fmt.Sprintf("%7.8f", e.MeanMotionDot)
@@ -316,7 +466,7 @@ func (e *Elements) MarshalTLE() (line0, line1, line2 string, err error) {
 	line1 = s
 
 	s = "2 " +
-		fmt.Sprintf("% -5s ", e.NoradCatId.Encode()) +
+		fmt.Sprintf("% -5s ", catNum) +
 		fmt.Sprintf("%8.4f ", e.Inclination) +
 		fmt.Sprintf("%8.4f ", e.RightAscension)
 
@@ -410,6 +560,8 @@ func checksum(s string) string {
 	return fmt.Sprintf("%d", sum%10)
 }
 
+// DoTLEs calls f with each group of lines, which must not be out of
+// step.  See TLEReader for something more robust.
 func DoTLEs(r *bufio.Reader, group int, f func(lines []string) error) error {
 
 	var (
@@ -439,3 +591,238 @@ func DoTLEs(r *bufio.Reader, group int, f func(lines []string) error) error {
 
 	return nil
 }
+
+// TLERecord holds the lines of one TLE as found by a TLEReader.
+type TLERecord struct {
+	// Name is the name line, which is "" if the TLE didn't have
+	// one.
+	Name string
+
+	Line1, Line2 string
+
+	// Line is the number of the record's first line (starting
+	// at 1).
+	Line int
+
+	// line1 and line2 are the numbers of the data lines.
+	line1, line2 int
+}
+
+// TLEReader finds TLEs in two-line or three-line form (or a mix).
+//
+// Record boundaries are found from the "1 " and "2 " line prefixes and
+// the catalog numbers that those lines must share.  Name lines are
+// optional.  Lines that can't be part of a TLE are skipped, so one
+// corrupt record doesn't shift every record after it.
+type TLEReader struct {
+	r *bufio.Reader
+
+	// Skip, if not nil, is called with each line that's skipped.
+	// Otherwise skipped lines are logged.
+	Skip func(line int, text string)
+
+	line int
+
+	// pending is a line that's been read but not used.
+	pending *tleLine
+}
+
+type tleLine struct {
+	text string
+	num  int
+}
+
+func NewTLEReader(r *bufio.Reader) *TLEReader {
+	return &TLEReader{
+		r: r,
+	}
+}
+
+// tleMinLineLen is a length that no name line (at most 24 characters
+// plus a "0 " prefix) should reach but that every (maybe trimmed) TLE
+// data line should.
+const tleMinLineLen = 32
+
+// isTLELine reports whether the text looks like the given line (1 or
+// 2) of a TLE.
+func isTLELine(s string, which byte) bool {
+	return tleMinLineLen <= len(s) && s[0] == which && s[1] == ' '
+}
+
+// sameCatalogNumber reports whether two TLE lines have the same
+// catalog number.
+func sameCatalogNumber(line1, line2 string) bool {
+	return strings.TrimSpace(line1[2:7]) == strings.TrimSpace(line2[2:7])
+}
+
+// next returns the next non-blank line or nil at EOF.
+func (r *TLEReader) next() (*tleLine, error) {
+	if l := r.pending; l != nil {
+		r.pending = nil
+		return l, nil
+	}
+	for {
+		s, err := r.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if s == "" && err == io.EOF {
+			return nil, nil
+		}
+		r.line++
+		s = strings.TrimRight(s, "\n\r")
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		return &tleLine{
+			text: s,
+			num:  r.line,
+		}, nil
+	}
+}
+
+func (r *TLEReader) skip(l *tleLine) {
+	if l == nil {
+		return
+	}
+	if r.Skip != nil {
+		r.Skip(l.num, l.text)
+		return
+	}
+	log.Printf("skipping line %d: '%s'", l.num, l.text)
+}
+
+// Read returns the next TLE or io.EOF.
+func (r *TLEReader) Read() (*TLERecord, error) {
+	var name *tleLine
+	for {
+		l, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if l == nil {
+			r.skip(name)
+			return nil, io.EOF
+		}
+
+		switch {
+		case isTLELine(l.text, '1'):
+			l2, err := r.next()
+			if err != nil {
+				return nil, err
+			}
+			if l2 == nil || !isTLELine(l2.text, '2') || !sameCatalogNumber(l.text, l2.text) {
+				// Corrupt record. Try again with the
+				// line after line 1.
+				r.skip(name)
+				r.skip(l)
+				name = nil
+				r.pending = l2
+				continue
+			}
+			rec := &TLERecord{
+				Line1: l.text,
+				Line2: l2.text,
+				Line:  l.num,
+				line1: l.num,
+				line2: l2.num,
+			}
+			if name != nil {
+				rec.Name = name.text
+				rec.Line = name.num
+			}
+			return rec, nil
+
+		case isTLELine(l.text, '2'):
+			// Line 2 without a line 1.
+			r.skip(name)
+			r.skip(l)
+			name = nil
+
+		default:
+			// A name, which is only used if a TLE
+			// follows.
+			r.skip(name)
+			name = l
+		}
+	}
+}
+
+// Parse parses the record with the given options.
+//
+// A ParseError's Line is the line in the input.
+func (rec *TLERecord) Parse(opts ParseOptions) (*Elements, error) {
+	e, err := ParseTLEWith(rec.Name, rec.Line1, rec.Line2, opts)
+	if err != nil {
+		if pe, is := err.(*ParseError); is && rec.line1 != 0 {
+			c := *pe
+			switch c.Line {
+			case 1:
+				c.Line = rec.line1
+			case 2:
+				c.Line = rec.line2
+			}
+			err = &c
+		}
+		return nil, recordError(err, "tle", 0, rec.Line)
+	}
+	return e, nil
+}
+
+// DoTLE calls f on each element set parsed from TLEs in two-line or
+// three-line form.
+func DoTLE(r *bufio.Reader, f func(Elements) error) error {
+	return doElements(&tleElementsReader{r: NewTLEReader(r), opts: DefaultParseOptions}, f)
+}
+
+// tleElementsReader parses the TLEs that a TLEReader finds.
+type tleElementsReader struct {
+	r      *TLEReader
+	opts   ParseOptions
+	record int
+}
+
+func (r *tleElementsReader) Read() (*Elements, error) {
+	rec, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	r.record++
+	e, err := rec.Parse(r.opts)
+	if err != nil {
+		return nil, recordError(err, "tle", r.record, 0)
+	}
+	return e, nil
+}
+
+// sniffTLE looks for a (complete) line that looks like a TLE data
+// line.
+func sniffTLE(peek []byte) bool {
+	lines := strings.Split(string(peek), "\n")
+	for _, line := range lines[0 : len(lines)-1] {
+		line = strings.TrimRight(line, "\r")
+		if isTLELine(line, '1') || isTLELine(line, '2') {
+			return true
+		}
+	}
+	return false
+}
+
+// tleWriter writes TLEs in three-line form.
+type tleWriter struct {
+	w    io.Writer
+	opts MarshalOptions
+}
+
+func (w *tleWriter) Write(e *Elements) error {
+	l0, l1, l2, err := e.MarshalTLEWith(w.opts)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.w, "%s\n%s\n%s\n", l0, l1, l2)
+	return err
+}
+
+func (w *tleWriter) Close() error {
+	return nil
+}
@@ -3,9 +3,11 @@ package gpelements
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestTLEMany(t *testing.T) {
@@ -78,3 +80,270 @@ func BenchmarkTLEParse(b *testing.B) {
 	}
 
 }
+
+func TestTLEReader(t *testing.T) {
+	var (
+		iss = strings.SplitN(testTLE, "\n", 3)
+		in  = strings.Join([]string{
+			// Two-line.
+			iss[1],
+			iss[2],
+			// Three-line.
+			iss[0],
+			iss[1],
+			iss[2],
+			// Corrupt: line 2 is missing.
+			"BROKEN",
+			iss[1],
+			iss[0],
+			iss[1],
+			iss[2],
+			// Orphan line 2.
+			iss[2],
+			"",
+			iss[0],
+			iss[1],
+			iss[2],
+		}, "\n")
+		r       = NewTLEReader(bufio.NewReader(strings.NewReader(in)))
+		skipped []int
+		names   []string
+		lines   []int
+	)
+
+	r.Skip = func(line int, text string) {
+		skipped = append(skipped, line)
+	}
+
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ParseTLE(rec.Name, rec.Line1, rec.Line2); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, strings.TrimSpace(rec.Name))
+		lines = append(lines, rec.Line)
+	}
+
+	if got, want := fmt.Sprint(names), "[ 0 ISS (ZARYA) 0 ISS (ZARYA) 0 ISS (ZARYA)]"; got != want {
+		t.Fatal(got)
+	}
+	if got, want := fmt.Sprint(lines), "[1 3 8 13]"; got != want {
+		t.Fatal(got)
+	}
+	if got, want := fmt.Sprint(skipped), "[6 7 11]"; got != want {
+		t.Fatal(got)
+	}
+}
+
+func TestParseTLEStrictness(t *testing.T) {
+	var (
+		lines = strings.SplitN(testTLE, "\n", 3)
+
+		// testTLE's first line has a stale checksum.
+		line1 = lines[1][0:68] + checksum(lines[1][0:68])
+
+		// Bad checksum.
+		badSum = lines[1][0:68] + "1"
+
+		// Trimmed and without a checksum.
+		short = strings.TrimRight(lines[2][0:68], " ")
+
+		// Malformed international designator.
+		badId = lines[1][0:9] + "9X067A  " + lines[1][17:68]
+	)
+	badId += checksum(badId)
+
+	for _, tc := range []struct {
+		line1, line2 string
+		level        Strictness
+		err          bool
+		warnings     int
+	}{
+		{line1, lines[2], Strict, false, 0},
+		{badSum, lines[2], Strict, true, 0},
+		{badSum, lines[2], Standard, false, 1},
+		{badSum, lines[2], Lenient, false, 0},
+		{line1, short, Strict, true, 0},
+		{line1, short, Standard, false, 1},
+		{line1, short, Lenient, false, 0},
+		{badId, lines[2], Strict, true, 0},
+		{badId, lines[2], Standard, false, 1},
+		{badId, lines[2], Lenient, false, 1},
+	} {
+		opts := ParseOptions{
+			Strictness: tc.level,
+		}
+		e, err := ParseTLEWith(lines[0], tc.line1, tc.line2, opts)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%s: expected an error for\n%s\n%s", tc.level, tc.line1, tc.line2)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", tc.level, err)
+		}
+		if len(e.Warnings) != tc.warnings {
+			t.Fatalf("%s: %v for\n%s\n%s", tc.level, e.Warnings, tc.line1, tc.line2)
+		}
+	}
+}
+
+func TestTLEEpochRoundTrip(t *testing.T) {
+	in, err := os.Open("data/test.tle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close() // Ignore error.
+
+	n := 0
+	f := func(lines []string) error {
+		n++
+		e, err := ParseTLE(lines[0], lines[1], lines[2])
+		if err != nil {
+			return err
+		}
+		epoch := time.Time(*e.Epoch)
+		if d := epoch.Sub(epoch.Truncate(24 * time.Hour)); d%tleDayUnit != 0 {
+			return fmt.Errorf("%s isn't a multiple of %s into the day", epoch, tleDayUnit)
+		}
+
+		_, line1, line2, err := e.MarshalTLE()
+		if err != nil {
+			return err
+		}
+		if got, want := line1[18:32], lines[1][18:32]; got != want {
+			return fmt.Errorf("epoch '%s' != '%s'", got, want)
+		}
+
+		again, err := ParseTLE(lines[0], line1, line2)
+		if err != nil {
+			return err
+		}
+		if !time.Time(*again.Epoch).Equal(epoch) {
+			return fmt.Errorf("%s != %s", time.Time(*again.Epoch), epoch)
+		}
+
+		return nil
+	}
+
+	if err := DoTLEs(bufio.NewReader(in), 3, f); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no TLEs")
+	}
+}
+
+func TestTLEDay(t *testing.T) {
+	for _, tc := range []struct {
+		day string
+		d   time.Duration
+		err bool
+	}{
+		{"264.51782528", 264*24*time.Hour + 51782528*tleDayUnit, false},
+		{"  1.5       ", 36 * time.Hour, false},
+		{"001.00000001", 24*time.Hour + tleDayUnit, false},
+		{"366.99999999", 366*24*time.Hour + 99999999*tleDayUnit, false},
+		{"1.2e3", 0, true},
+		{"-12.5", 0, true},
+		{".", 0, true},
+	} {
+		d, err := parseTLEDay(tc.day)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("expected an error for '%s'", tc.day)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d != tc.d {
+			t.Fatalf("'%s': %s != %s", tc.day, d, tc.d)
+		}
+	}
+
+	// Rounding to the nearest 1e-8 day.
+	for _, tc := range []struct {
+		d   time.Duration
+		day string
+	}{
+		{tleDayUnit / 2, "000.00000001"},
+		{tleDayUnit/2 - 1, "000.00000000"},
+		{24*time.Hour - 1, "001.00000000"},
+	} {
+		if got := formatTLEDay(tc.d); got != tc.day {
+			t.Fatalf("%s: %s != %s", tc.d, got, tc.day)
+		}
+	}
+}
+
+func TestYearWindow(t *testing.T) {
+	for _, tc := range []struct {
+		w    YearWindow
+		yy   int
+		year int
+	}{
+		{YearWindow{}, 56, 1956},
+		{YearWindow{}, 55, 2055},
+		{YearWindow{}, 0, 2000},
+		{YearWindow{Start: 1900}, 56, 1956},
+		{YearWindow{Start: 1900}, 0, 1900},
+		{YearWindow{Start: 1980}, 79, 2079},
+		{SlidingYearWindow(time.Date(2070, 6, 1, 0, 0, 0, 0, time.UTC), 10), 80, 2080},
+		{SlidingYearWindow(time.Date(2070, 6, 1, 0, 0, 0, 0, time.UTC), 10), 81, 1981},
+	} {
+		if got := tc.w.Expand(tc.yy); got != tc.year {
+			t.Fatalf("%v.Expand(%d) = %d != %d", tc.w, tc.yy, got, tc.year)
+		}
+		yy, err := tc.w.TwoDigits(tc.year)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if yy != tc.yy {
+			t.Fatalf("%v.TwoDigits(%d) = %d != %d", tc.w, tc.year, yy, tc.yy)
+		}
+	}
+
+	if _, err := (YearWindow{}).TwoDigits(1955); err == nil {
+		t.Fatal("expected an error for 1955")
+	}
+	if _, err := (YearWindow{}).TwoDigits(2056); err == nil {
+		t.Fatal("expected an error for 2056")
+	}
+}
+
+func TestTLEYearWindow(t *testing.T) {
+	e := testElements(t)
+
+	epoch := Time(time.Date(2060, 3, 1, 0, 0, 0, 0, time.UTC))
+	e.Epoch = &epoch
+	if _, _, _, err := e.MarshalTLE(); err == nil {
+		t.Fatal("expected an error for a 2060 epoch")
+	}
+
+	years := YearWindow{Start: 1970}
+	line0, line1, line2, err := e.MarshalTLEWith(MarshalOptions{Years: years})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default window would make the epoch 1960.
+	again, err := ParseTLEWith(line0, line1, line2, ParseOptions{Years: years})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !time.Time(*again.Epoch).Equal(time.Time(epoch)) {
+		t.Fatal(time.Time(*again.Epoch))
+	}
+	if again.LaunchYear != 1998 {
+		t.Fatal(again.LaunchYear)
+	}
+}
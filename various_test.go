@@ -14,6 +14,16 @@ var (
 2 25544  51.6432 245.8351 0000884 104.2674 236.9442 15.48952759246507`
 )
 
+// testElements parses testTLE.
+func testElements(t *testing.T) *Elements {
+	lines := strings.SplitN(testTLE, "\n", 3)
+	e, err := ParseTLE(lines[0], lines[1], lines[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
 func sameTLELine(line, check string) bool {
 
 	if strings.TrimSpace(line) == strings.TrimSpace(check) {
@@ -18,10 +18,10 @@ import (
 // range").
 
 // NextAlpha5Num generates a new NORAD catalogy number using the "Alpha-5"
-// scheme and updates the Name.
+// scheme.  State 0 gives A0000 (100000).
 func NextAlpha5Num(state int64) (string, int64, error) {
 	var (
-		blocks = "ABCDEFGHIJKMPQRSTUVWXYZ"
+		blocks = alpha5Letters
 		block  = state / 10000
 		rem    = state % 10000
 	)
@@ -47,7 +47,7 @@ func (e *Elements) Reassign(state int64) (int64, error) {
 	if err != nil {
 		return state, err
 	}
-	e.NoradCatId = NoradCatId(id)
+	e.NoradCatId = NoradCatId(id).Decode()
 	return next, nil
 }
 
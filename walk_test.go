@@ -2,20 +2,11 @@ package gpelements
 
 import (
 	"fmt"
-	"strings"
 	"testing"
 )
 
 func TestWalk(t *testing.T) {
-	lines := strings.SplitN(testTLE, "\n", 3)
-	if len(lines) != 3 {
-		t.Fatal(len(lines))
-	}
-
-	e, err := ParseTLE(lines[0], lines[1], lines[2])
-	if err != nil {
-		t.Fatal(err)
-	}
+	e := testElements(t)
 
 	for i := 0; i < 5; i++ {
 		err := e.Copy().Walk(2, 4)
@@ -1,11 +1,22 @@
 package gpelements
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
 
+// NewElements returns Elements with the OMM metadata that's implied
+// by a TLE.
 func NewElements() *Elements {
 	return &Elements{
 		OMMId:      "CCSDS_OMM_VERS",
 		OMMVersion: "2.0",
+
+		CenterName:        "EARTH",
+		RefFrame:          "TEME",
+		TimeSystem:        "UTC",
+		MeanElementTheory: "SGP4",
 	}
 }
 
@@ -13,3 +24,131 @@ type ElementsList struct {
 	XMLName xml.Name   `xml:"ndm"`
 	Es      []Elements `xml:"omm"`
 }
+
+// MarshalXML writes the Extras as USER_DEFINED parameters after any
+// UserDefined ones.
+func (e Elements) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	type plain Elements // Without methods.
+
+	if 0 < len(e.Extras) {
+		c := e.Copy()
+		for _, x := range e.Extras {
+			c.UserDefined = append(c.UserDefined, UserDefined{
+				Parameter: x.Key,
+				Value:     x.Text(),
+			})
+		}
+		e = *c
+	}
+
+	// At the top level, start is named after the type.
+	start.Name = xml.Name{Local: "omm"}
+
+	return enc.EncodeElement(plain(e), start)
+}
+
+// DoXML calls f on each <omm> element as it's read from the input.
+//
+// The input can be an <ndm> document or just a sequence of <omm>
+// elements without a wrapper.  Only one <omm> element is in memory at
+// a time.
+func DoXML(r io.Reader, f func(Elements) error) error {
+	return doElements(newXMLReader(r), f)
+}
+
+// xmlReader reads <omm> elements.
+type xmlReader struct {
+	d      *xml.Decoder
+	record int
+	ended  bool
+}
+
+func newXMLReader(r io.Reader) *xmlReader {
+	return &xmlReader{
+		d: xml.NewDecoder(r),
+	}
+}
+
+func (r *xmlReader) Read() (*Elements, error) {
+	if r.ended {
+		return nil, io.EOF
+	}
+	e, ok, err := nextXML(r.d)
+	if err == io.EOF {
+		return nil, err
+	}
+	r.record++
+	if err != nil {
+		r.ended = !ok
+		line := 0
+		if se, is := err.(*xml.SyntaxError); is {
+			line = se.Line
+		}
+		return nil, recordError(err, "xml", r.record, line)
+	}
+	return e, nil
+}
+
+// xmlWriter writes an <ndm> document.
+type xmlWriter struct {
+	w io.Writer
+	n int
+}
+
+func (w *xmlWriter) Write(e *Elements) error {
+	bs, err := xml.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if w.n == 0 {
+		if _, err = io.WriteString(w.w, "<ndm>\n"); err != nil {
+			return err
+		}
+	}
+	w.n++
+	_, err = fmt.Fprintf(w.w, "%s\n", bs)
+	return err
+}
+
+func (w *xmlWriter) Close() error {
+	if w.n == 0 {
+		if _, err := io.WriteString(w.w, "<ndm>\n"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w.w, "</ndm>\n")
+	return err
+}
+
+// nextXML finds the next <omm> element, which can be at any depth,
+// and decodes it.
+//
+// Returns io.EOF when there are no more elements.  After another
+// error, the returned bool says whether the decoder can go on to the
+// next element.
+func nextXML(d *xml.Decoder) (*Elements, bool, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, false, err
+		}
+		start, is := tok.(xml.StartElement)
+		if !is || start.Name.Local != "omm" {
+			continue
+		}
+		e := NewElements()
+		if err = d.DecodeElement(e, &start); err != nil {
+			_, syntax := err.(*xml.SyntaxError)
+			return nil, !syntax && err != io.ErrUnexpectedEOF, err
+		}
+		if err = e.UseUTC(); err != nil {
+			return nil, true, &ParseError{
+				Format: "xml",
+				Field:  "TIME_SYSTEM",
+				Text:   e.TimeSystem,
+				Err:    err,
+			}
+		}
+		return e, true, nil
+	}
+}
@@ -3,6 +3,8 @@ package gpelements
 import (
 	"encoding/xml"
 	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -25,3 +27,51 @@ func TestXML(t *testing.T) {
 		}
 	}
 }
+
+func TestDoXML(t *testing.T) {
+	filename := "data/test.xml"
+
+	in, err := os.Open(filename)
+	if err != nil {
+		t.Skipf("Couldn't open %s: %s", filename, err)
+	}
+	defer in.Close() // Ignore error.
+
+	n := 0
+	err = DoXML(in, func(e Elements) error {
+		if e.Epoch == nil {
+			t.Fatalf("no epoch for %s", e.Name)
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 76 {
+		t.Fatal(n)
+	}
+}
+
+func TestDoXMLBare(t *testing.T) {
+	bs, err := xml.Marshal(NewElements())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No <ndm> wrapper.
+	var (
+		in = strings.NewReader(string(bs) + "\n" + string(bs) + "\n")
+		n  = 0
+	)
+	err = DoXML(in, func(e Elements) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatal(n)
+	}
+}